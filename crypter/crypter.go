@@ -0,0 +1,186 @@
+package crypter
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"io"
+
+	"coding.net/cherrysd/wxserver/util"
+)
+
+// 加解密错误
+var (
+	ErrInvalidAESKey    = errors.New("crypter: invalid EncodingAESKey")
+	ErrInvalidSignature = errors.New("crypter: invalid msg_signature")
+	ErrInvalidAppID     = errors.New("crypter: appid mismatch")
+	ErrInvalidPadding   = errors.New("crypter: invalid PKCS#7 padding")
+	ErrInvalidContent   = errors.New("crypter: invalid decrypted content")
+)
+
+// 微信加密使用32字节作为PKCS#7补位的块大小
+const blockSize = 32
+
+// EncryptedRequest 服务器传来的加密消息体
+type EncryptedRequest struct {
+	XMLName    xml.Name `xml:"xml"`
+	ToUserName string   `xml:"ToUserName"`
+	Encrypt    string   `xml:"Encrypt"`
+}
+
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// encryptedResponse 回复给服务器的加密消息体
+type encryptedResponse struct {
+	XMLName      xml.Name `xml:"xml"`
+	Encrypt      cdata    `xml:"Encrypt"`
+	MsgSignature cdata    `xml:"MsgSignature"`
+	TimeStamp    string   `xml:"TimeStamp"`
+	Nonce        cdata    `xml:"Nonce"`
+}
+
+// Crypter 安全模式消息加解密器
+type Crypter struct {
+	token  string
+	appid  string
+	aesKey []byte
+}
+
+// NewCrypter 创建加解密器，encodingAESKey为公众平台配置的43位EncodingAESKey
+func NewCrypter(token string, encodingAESKey string, appid string) (*Crypter, error) {
+	if len(encodingAESKey) != 43 {
+		return nil, ErrInvalidAESKey
+	}
+	aesKey, err := base64.StdEncoding.DecodeString(encodingAESKey + "=")
+	if err != nil || len(aesKey) != 32 {
+		return nil, ErrInvalidAESKey
+	}
+	c := new(Crypter)
+	c.token = token
+	c.appid = appid
+	c.aesKey = aesKey
+	return c, nil
+}
+
+// ParseRequest 解析加密消息外层的XML结构
+func ParseRequest(contentBytes []byte) (EncryptedRequest, error) {
+	req := EncryptedRequest{}
+	err := xml.Unmarshal(contentBytes, &req)
+	return req, err
+}
+
+// DecryptMsg 校验msg_signature并解密消息，返回明文XML
+func (c *Crypter) DecryptMsg(msgSignature string, timestamp string, nonce string, contentBytes []byte) ([]byte, error) {
+	req, err := ParseRequest(contentBytes)
+	if err != nil {
+		return nil, err
+	}
+	if !util.CheckMsgSignature(c.token, timestamp, nonce, req.Encrypt, msgSignature) {
+		return nil, ErrInvalidSignature
+	}
+	return c.Decrypt(req.Encrypt)
+}
+
+// EncryptMsg 加密明文回复并签名，返回可直接回复给服务器的XML
+func (c *Crypter) EncryptMsg(plain []byte, timestamp string, nonce string) ([]byte, error) {
+	encrypt, err := c.Encrypt(plain)
+	if err != nil {
+		return nil, err
+	}
+	resp := encryptedResponse{}
+	resp.Encrypt.Value = encrypt
+	resp.MsgSignature.Value = util.GenMsgSignature(c.token, timestamp, nonce, encrypt)
+	resp.TimeStamp = timestamp
+	resp.Nonce.Value = nonce
+	return xml.Marshal(resp)
+}
+
+// Decrypt 解密Encrypt字段内容并校验AppID
+func (c *Crypter) Decrypt(encrypt string) ([]byte, error) {
+	cipherText, err := base64.StdEncoding.DecodeString(encrypt)
+	if err != nil {
+		return nil, err
+	}
+	if len(cipherText) == 0 || len(cipherText)%aes.BlockSize != 0 {
+		return nil, ErrInvalidContent
+	}
+
+	block, err := aes.NewCipher(c.aesKey)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(cipherText))
+	cipher.NewCBCDecrypter(block, c.aesKey[:aes.BlockSize]).CryptBlocks(plain, cipherText)
+
+	plain, err = pkcs7Unpad(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	// 16字节随机串 + 4字节消息长度 + 消息 + AppID
+	if len(plain) < 20 {
+		return nil, ErrInvalidContent
+	}
+	msgLen := int(binary.BigEndian.Uint32(plain[16:20]))
+	if msgLen < 0 || 20+msgLen > len(plain) {
+		return nil, ErrInvalidContent
+	}
+	msg := plain[20 : 20+msgLen]
+	if string(plain[20+msgLen:]) != c.appid {
+		return nil, ErrInvalidAppID
+	}
+	return msg, nil
+}
+
+// Encrypt 加密明文消息，返回Base64编码的密文
+func (c *Crypter) Encrypt(msg []byte) (string, error) {
+	var buf bytes.Buffer
+	random := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, random); err != nil {
+		return "", err
+	}
+	msgLen := make([]byte, 4)
+	binary.BigEndian.PutUint32(msgLen, uint32(len(msg)))
+	buf.Write(random)
+	buf.Write(msgLen)
+	buf.Write(msg)
+	buf.WriteString(c.appid)
+
+	plain := pkcs7Pad(buf.Bytes())
+	block, err := aes.NewCipher(c.aesKey)
+	if err != nil {
+		return "", err
+	}
+	cipherText := make([]byte, len(plain))
+	cipher.NewCBCEncrypter(block, c.aesKey[:aes.BlockSize]).CryptBlocks(cipherText, plain)
+	return base64.StdEncoding.EncodeToString(cipherText), nil
+}
+
+func pkcs7Pad(data []byte) []byte {
+	padding := blockSize - len(data)%blockSize
+	return append(data, bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+func pkcs7Unpad(data []byte) ([]byte, error) {
+	length := len(data)
+	if length == 0 {
+		return nil, ErrInvalidPadding
+	}
+	padding := int(data[length-1])
+	if padding < 1 || padding > blockSize || padding > length {
+		return nil, ErrInvalidPadding
+	}
+	for _, b := range data[length-padding:] {
+		if int(b) != padding {
+			return nil, ErrInvalidPadding
+		}
+	}
+	return data[:length-padding], nil
+}
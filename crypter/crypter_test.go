@@ -0,0 +1,105 @@
+package crypter
+
+import (
+	"bytes"
+	"testing"
+
+	"coding.net/cherrysd/wxserver/util"
+)
+
+// 微信官方加解密示例中的参数
+const (
+	sampleToken          = "pamtest"
+	sampleEncodingAESKey = "abcdefghijklmnopqrstuvwxyz0123456789ABCDEFG"
+	sampleAppID          = "wxb11529c136998cb6"
+	sampleTimestamp      = "1409304348"
+	sampleNonce          = "xxxxxx"
+	sampleMsg            = "<xml><ToUserName><![CDATA[oia2Tj我是中文jewbmiOUlr6X-1crbLOvLw]]></ToUserName><FromUserName><![CDATA[gh_7f083739789a]]></FromUserName><CreateTime>1407743423</CreateTime><MsgType><![CDATA[video]]></MsgType><Video><MediaId><![CDATA[eYJ1MbwPRJtOvIEabaxHs7TX2D-HV71s79GUxqdUkjm6Gs2Ed1KF3ulAOA9H1xG0]]></MediaId><Title><![CDATA[testCallBackReplyVideo]]></Title><Description><![CDATA[testCallBackReplyVideo]]></Description></Video></xml>"
+)
+
+// sampleEncrypt 以固定随机串"0123456789abcdef"加密sampleMsg的结果，由openssl独立生成：
+// openssl enc -aes-256-cbc -nopad -K <AESKey> -iv <AESKey前16字节>
+const sampleEncrypt = "Q3stYC6hdFzMh9T8HCvyDPSKQxeprf7Esg3qsv6KkaKZvN92IlkZRc+Aa1Hmcdl6ob0iiO+IeVpu0a3CImoio4FAB1o4XEUT3Uv2WBiIGtUCv7IJM7Uz34jtMt6awvzJeh0cQDZAXjlJ0yk2m+po9s9RmRTEx85Jsa405vwjM03WeKX+mPZFjzXz8Z/jUYiUB6CRC2fSLp5LkDYgSUChZO5cKqzISY79NhTAd6X9f+jOiiELqmYw6jiGYsOsCs5jFT+sQh2rNNjw5ea6P5qP/hDSsYN6GpdEpTUmR25iFzdY5ZsH1y/RAHQ44a7ckpp2uZseP0aqpoFFGHcXNL4F4EC2tlnOjoIT0R22BczA2pfIAtiHnpJ0hU1vnm6N9gbJY+ELUv5oTqZWYF7s+Gy1awk/APqH+y1etWiUiwt8CVKcXaEgAVwifEvghiLkKBwNR4j0FKLeLu0EUzoe6YLCxw94X1+uV2P8t+IeMibVqXEdnjAIFXoE5TuLZq22ecoH2UNTXHwZNK6/JMA6s1SE0DKUYREY1l//6YJyJ4ypj6s9Ns3ElEpv+JArld6o+WyjyeTre8+iFf1BfOg0ZV00x4WNXxWf1zOIiFD9Ln1FDAJ5U8DB54FBW813xbAiZFXY"
+
+const sampleMsgSignature = "7454da4fd3ccd35ada63d729942c72e95a4426b9"
+
+func newSampleCrypter(t *testing.T) *Crypter {
+	c, err := NewCrypter(sampleToken, sampleEncodingAESKey, sampleAppID)
+	if err != nil {
+		t.Fatalf("NewCrypter: %v", err)
+	}
+	return c
+}
+
+func TestDecryptSample(t *testing.T) {
+	c := newSampleCrypter(t)
+	body := []byte("<xml><ToUserName><![CDATA[gh_7f083739789a]]></ToUserName><Encrypt><![CDATA[" + sampleEncrypt + "]]></Encrypt></xml>")
+	msg, err := c.DecryptMsg(sampleMsgSignature, sampleTimestamp, sampleNonce, body)
+	if err != nil {
+		t.Fatalf("DecryptMsg: %v", err)
+	}
+	if string(msg) != sampleMsg {
+		t.Errorf("DecryptMsg = %q, want %q", msg, sampleMsg)
+	}
+
+	if _, err := c.DecryptMsg("0000000000000000000000000000000000000000", sampleTimestamp, sampleNonce, body); err != ErrInvalidSignature {
+		t.Errorf("DecryptMsg with bad signature err = %v, want %v", err, ErrInvalidSignature)
+	}
+
+	other, err := NewCrypter(sampleToken, sampleEncodingAESKey, "wx0000000000000000")
+	if err != nil {
+		t.Fatalf("NewCrypter: %v", err)
+	}
+	if _, err := other.Decrypt(sampleEncrypt); err != ErrInvalidAppID {
+		t.Errorf("Decrypt with other appid err = %v, want %v", err, ErrInvalidAppID)
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	c := newSampleCrypter(t)
+	for _, msg := range []string{"", "a", sampleMsg} {
+		resp, err := c.EncryptMsg([]byte(msg), sampleTimestamp, sampleNonce)
+		if err != nil {
+			t.Fatalf("EncryptMsg(%q): %v", msg, err)
+		}
+		req, err := ParseRequest(resp)
+		if err != nil {
+			t.Fatalf("ParseRequest: %v", err)
+		}
+		signature := util.GenMsgSignature(sampleToken, sampleTimestamp, sampleNonce, req.Encrypt)
+		plain, err := c.DecryptMsg(signature, sampleTimestamp, sampleNonce, resp)
+		if err != nil {
+			t.Fatalf("DecryptMsg(%q): %v", msg, err)
+		}
+		if string(plain) != msg {
+			t.Errorf("round trip = %q, want %q", plain, msg)
+		}
+	}
+}
+
+func TestPKCS7Unpad(t *testing.T) {
+	cases := []struct {
+		name string
+		data []byte
+		want []byte
+		err  error
+	}{
+		{"full block", bytes.Repeat([]byte{32}, 32), []byte{}, nil},
+		{"one byte", append(bytes.Repeat([]byte{'a'}, 31), 1), bytes.Repeat([]byte{'a'}, 31), nil},
+		{"three bytes", append(bytes.Repeat([]byte{'a'}, 29), 3, 3, 3), bytes.Repeat([]byte{'a'}, 29), nil},
+		{"mismatched pad byte", append(bytes.Repeat([]byte{'a'}, 29), 3, 2, 3), nil, ErrInvalidPadding},
+		{"zero", append(bytes.Repeat([]byte{'a'}, 31), 0), nil, ErrInvalidPadding},
+		{"larger than block", append(bytes.Repeat([]byte{'a'}, 31), 33), nil, ErrInvalidPadding},
+		{"empty", nil, nil, ErrInvalidPadding},
+	}
+	for _, tc := range cases {
+		got, err := pkcs7Unpad(tc.data)
+		if err != tc.err {
+			t.Errorf("%s: err = %v, want %v", tc.name, err, tc.err)
+			continue
+		}
+		if err == nil && !bytes.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package custom
+
+import (
+	"context"
+
+	"coding.net/cherrysd/wxserver/message"
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const sendPath = "/cgi-bin/message/custom/send"
+
+// 客服消息类型枚举
+const (
+	TextMsg            = "text"
+	ImageMsg           = "image"
+	VoiceMsg           = "voice"
+	VideoMsg           = "video"
+	MusicMsg           = "music"
+	NewsMsg            = "news"
+	MPNewsMsg          = "mpnews"
+	MenuMsg            = "msgmenu"
+	MiniProgramPageMsg = "miniprogrampage"
+)
+
+// Message 客服消息JSON
+type Message struct {
+	ToUser          string           `json:"touser"`
+	MsgType         string           `json:"msgtype"`
+	Text            *Text            `json:"text,omitempty"`
+	Image           *Media           `json:"image,omitempty"`
+	Voice           *Media           `json:"voice,omitempty"`
+	Video           *Video           `json:"video,omitempty"`
+	Music           *Music           `json:"music,omitempty"`
+	News            *News            `json:"news,omitempty"`
+	MPNews          *Media           `json:"mpnews,omitempty"`
+	MsgMenu         *MsgMenu         `json:"msgmenu,omitempty"`
+	MiniProgramPage *MiniProgramPage `json:"miniprogrampage,omitempty"`
+	CustomService   *CustomService   `json:"customservice,omitempty"`
+}
+
+// Text 文本消息内容
+type Text struct {
+	Content string `json:"content"`
+}
+
+// Media 图片、语音、图文(mpnews)消息内容
+type Media struct {
+	MediaID string `json:"media_id"`
+}
+
+// Video 视频消息内容
+type Video struct {
+	MediaID      string `json:"media_id"`
+	ThumbMediaID string `json:"thumb_media_id"`
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// Music 音乐消息内容
+type Music struct {
+	Title        string `json:"title,omitempty"`
+	Description  string `json:"description,omitempty"`
+	MusicURL     string `json:"musicurl"`
+	HQMusicURL   string `json:"hqmusicurl"`
+	ThumbMediaID string `json:"thumb_media_id"`
+}
+
+// News 图文消息(点击跳转到外链)内容
+type News struct {
+	Articles []Article `json:"articles"`
+}
+
+// Article 图文消息子项
+type Article struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	PicURL      string `json:"picurl"`
+}
+
+// MsgMenu 菜单消息内容
+type MsgMenu struct {
+	HeadContent string        `json:"head_content"`
+	List        []MsgMenuItem `json:"list"`
+	TailContent string        `json:"tail_content"`
+}
+
+// MsgMenuItem 菜单消息选项
+type MsgMenuItem struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+}
+
+// MiniProgramPage 小程序卡片消息内容
+type MiniProgramPage struct {
+	Title        string `json:"title"`
+	AppID        string `json:"appid"`
+	PagePath     string `json:"pagepath"`
+	ThumbMediaID string `json:"thumb_media_id"`
+}
+
+// CustomService 指定发送消息的客服帐号
+type CustomService struct {
+	KfAccount string `json:"kf_account"`
+}
+
+// Send 发送客服消息
+func Send(svr *server.Server, msg *Message) error {
+	return SendContext(context.Background(), svr, msg)
+}
+
+// SendContext 发送客服消息
+func SendContext(ctx context.Context, svr *server.Server, msg *Message) error {
+	return svr.Client().PostJSONContext(ctx, sendPath, nil, msg, nil)
+}
+
+// SendText 发送文本消息
+func SendText(svr *server.Server, toUser string, content string) error {
+	return SendTextContext(context.Background(), svr, toUser, content)
+}
+
+// SendTextContext 发送文本消息
+func SendTextContext(ctx context.Context, svr *server.Server, toUser string, content string) error {
+	msg := Message{ToUser: toUser, MsgType: TextMsg}
+	msg.Text = &Text{Content: content}
+	return SendContext(ctx, svr, &msg)
+}
+
+// SendImage 发送图片消息
+func SendImage(svr *server.Server, toUser string, mediaID string) error {
+	return SendImageContext(context.Background(), svr, toUser, mediaID)
+}
+
+// SendImageContext 发送图片消息
+func SendImageContext(ctx context.Context, svr *server.Server, toUser string, mediaID string) error {
+	msg := Message{ToUser: toUser, MsgType: ImageMsg}
+	msg.Image = &Media{MediaID: mediaID}
+	return SendContext(ctx, svr, &msg)
+}
+
+// SendVoice 发送语音消息
+func SendVoice(svr *server.Server, toUser string, mediaID string) error {
+	return SendVoiceContext(context.Background(), svr, toUser, mediaID)
+}
+
+// SendVoiceContext 发送语音消息
+func SendVoiceContext(ctx context.Context, svr *server.Server, toUser string, mediaID string) error {
+	msg := Message{ToUser: toUser, MsgType: VoiceMsg}
+	msg.Voice = &Media{MediaID: mediaID}
+	return SendContext(ctx, svr, &msg)
+}
+
+// SendVideo 发送视频消息
+func SendVideo(svr *server.Server, toUser string, video *message.Video) error {
+	return SendVideoContext(context.Background(), svr, toUser, video)
+}
+
+// SendVideoContext 发送视频消息
+func SendVideoContext(ctx context.Context, svr *server.Server, toUser string, video *message.Video) error {
+	msg := Message{ToUser: toUser, MsgType: VideoMsg}
+	msg.Video = &Video{
+		MediaID:      video.MediaID,
+		ThumbMediaID: video.ThumbMediaID,
+		Title:        video.Title,
+		Description:  video.Description,
+	}
+	return SendContext(ctx, svr, &msg)
+}
+
+// SendMusic 发送音乐消息
+func SendMusic(svr *server.Server, toUser string, music *message.Music) error {
+	return SendMusicContext(context.Background(), svr, toUser, music)
+}
+
+// SendMusicContext 发送音乐消息
+func SendMusicContext(ctx context.Context, svr *server.Server, toUser string, music *message.Music) error {
+	msg := Message{ToUser: toUser, MsgType: MusicMsg}
+	msg.Music = &Music{
+		Title:        music.Title,
+		Description:  music.Description,
+		MusicURL:     music.MusicURL,
+		HQMusicURL:   music.HQMusicURL,
+		ThumbMediaID: music.ThumbMediaID,
+	}
+	return SendContext(ctx, svr, &msg)
+}
+
+// SendNews 发送图文消息(点击跳转到外链)
+func SendNews(svr *server.Server, toUser string, articles []message.Article) error {
+	return SendNewsContext(context.Background(), svr, toUser, articles)
+}
+
+// SendNewsContext 发送图文消息(点击跳转到外链)
+func SendNewsContext(ctx context.Context, svr *server.Server, toUser string, articles []message.Article) error {
+	msg := Message{ToUser: toUser, MsgType: NewsMsg}
+	msg.News = new(News)
+	for index := 0; index < len(articles); index++ {
+		article := Article{}
+		article.Title = articles[index].Title
+		article.Description = articles[index].Description
+		article.URL = articles[index].URL
+		article.PicURL = articles[index].PicURL
+		msg.News.Articles = append(msg.News.Articles, article)
+	}
+	return SendContext(ctx, svr, &msg)
+}
+
+// SendMPNews 发送图文消息(点击跳转到图文消息页面)
+func SendMPNews(svr *server.Server, toUser string, mediaID string) error {
+	return SendMPNewsContext(context.Background(), svr, toUser, mediaID)
+}
+
+// SendMPNewsContext 发送图文消息(点击跳转到图文消息页面)
+func SendMPNewsContext(ctx context.Context, svr *server.Server, toUser string, mediaID string) error {
+	msg := Message{ToUser: toUser, MsgType: MPNewsMsg}
+	msg.MPNews = &Media{MediaID: mediaID}
+	return SendContext(ctx, svr, &msg)
+}
+
+// SendMenu 发送菜单消息
+func SendMenu(svr *server.Server, toUser string, menu *MsgMenu) error {
+	return SendMenuContext(context.Background(), svr, toUser, menu)
+}
+
+// SendMenuContext 发送菜单消息
+func SendMenuContext(ctx context.Context, svr *server.Server, toUser string, menu *MsgMenu) error {
+	msg := Message{ToUser: toUser, MsgType: MenuMsg}
+	msg.MsgMenu = menu
+	return SendContext(ctx, svr, &msg)
+}
+
+// SendMiniProgramPage 发送小程序卡片消息
+func SendMiniProgramPage(svr *server.Server, toUser string, page *MiniProgramPage) error {
+	return SendMiniProgramPageContext(context.Background(), svr, toUser, page)
+}
+
+// SendMiniProgramPageContext 发送小程序卡片消息
+func SendMiniProgramPageContext(ctx context.Context, svr *server.Server, toUser string, page *MiniProgramPage) error {
+	msg := Message{ToUser: toUser, MsgType: MiniProgramPageMsg}
+	msg.MiniProgramPage = page
+	return SendContext(ctx, svr, &msg)
+}
@@ -0,0 +1,5 @@
+module coding.net/cherrysd/wxserver
+
+go 1.16
+
+require gopkg.in/yaml.v2 v2.4.0
@@ -0,0 +1,242 @@
+package media
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const (
+	addMaterialPath    = "/cgi-bin/material/add_material"
+	addNewsPath        = "/cgi-bin/material/add_news"
+	updateNewsPath     = "/cgi-bin/material/update_news"
+	getMaterialPath    = "/cgi-bin/material/get_material"
+	deleteMaterialPath = "/cgi-bin/material/del_material"
+	materialCountPath  = "/cgi-bin/material/get_materialcount"
+	batchGetPath       = "/cgi-bin/material/batchget_material"
+)
+
+// ErrVideoDescription 上传视频素材时缺少标题或简介
+var ErrVideoDescription = errors.New("media: video title and introduction are required")
+
+// Material 永久素材上传结果，图片素材会返回URL
+type Material struct {
+	MediaID string `json:"media_id"`
+	URL     string `json:"url"`
+}
+
+// VideoDescription 上传视频素材时的描述信息
+type VideoDescription struct {
+	Title        string `json:"title"`
+	Introduction string `json:"introduction"`
+}
+
+// Video 视频素材信息
+type Video struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	DownURL     string `json:"down_url"`
+}
+
+// Article 图文素材子项
+type Article struct {
+	Title              string `json:"title"`
+	ThumbMediaID       string `json:"thumb_media_id"`
+	Author             string `json:"author,omitempty"`
+	Digest             string `json:"digest,omitempty"`
+	ShowCoverPic       int    `json:"show_cover_pic"`
+	Content            string `json:"content"`
+	ContentSourceURL   string `json:"content_source_url,omitempty"`
+	NeedOpenComment    int    `json:"need_open_comment,omitempty"`
+	OnlyFansCanComment int    `json:"only_fans_can_comment,omitempty"`
+	URL                string `json:"url,omitempty"`
+	ThumbURL           string `json:"thumb_url,omitempty"`
+}
+
+// MaterialCount 永久素材总数
+type MaterialCount struct {
+	VoiceCount int `json:"voice_count"`
+	VideoCount int `json:"video_count"`
+	ImageCount int `json:"image_count"`
+	NewsCount  int `json:"news_count"`
+}
+
+// MaterialItem 素材列表子项，图文素材的内容在Content中
+type MaterialItem struct {
+	MediaID    string `json:"media_id"`
+	Name       string `json:"name"`
+	UpdateTime int64  `json:"update_time"`
+	URL        string `json:"url"`
+	Content    *struct {
+		NewsItem []Article `json:"news_item"`
+	} `json:"content,omitempty"`
+}
+
+// MaterialList 素材列表
+type MaterialList struct {
+	TotalCount int            `json:"total_count"`
+	ItemCount  int            `json:"item_count"`
+	Item       []MaterialItem `json:"item"`
+}
+
+type mediaIDRequest struct {
+	MediaID string `json:"media_id"`
+}
+
+// AddMaterial 上传图片、语音、缩略图永久素材
+func AddMaterial(svr *server.Server, mediaType Type, fileName string, reader io.Reader) (*Material, error) {
+	return AddMaterialContext(context.Background(), svr, mediaType, fileName, reader)
+}
+
+// AddMaterialContext 上传图片、语音、缩略图永久素材
+func AddMaterialContext(ctx context.Context, svr *server.Server, mediaType Type, fileName string, reader io.Reader) (*Material, error) {
+	return addMaterial(ctx, svr, mediaType, fileName, reader, nil)
+}
+
+// AddVideo 上传视频永久素材，description的标题与简介不能为空
+func AddVideo(svr *server.Server, fileName string, reader io.Reader, description *VideoDescription) (*Material, error) {
+	return AddVideoContext(context.Background(), svr, fileName, reader, description)
+}
+
+// AddVideoContext 上传视频永久素材，description的标题与简介不能为空
+func AddVideoContext(ctx context.Context, svr *server.Server, fileName string, reader io.Reader, description *VideoDescription) (*Material, error) {
+	if description == nil || description.Title == "" || description.Introduction == "" {
+		return nil, ErrVideoDescription
+	}
+	content, err := json.Marshal(description)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]string{"description": string(content)}
+	return addMaterial(ctx, svr, VideoType, fileName, reader, fields)
+}
+
+func addMaterial(ctx context.Context, svr *server.Server, mediaType Type, fileName string, reader io.Reader, fields map[string]string) (*Material, error) {
+	params := url.Values{}
+	params.Set("type", string(mediaType))
+	result := new(Material)
+	err := svr.Client().UploadContext(ctx, addMaterialPath, params, mediaFieldName, fileName, reader, fields, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AddNews 新增永久图文素材，返回素材ID
+func AddNews(svr *server.Server, articles []Article) (string, error) {
+	return AddNewsContext(context.Background(), svr, articles)
+}
+
+// AddNewsContext 新增永久图文素材，返回素材ID
+func AddNewsContext(ctx context.Context, svr *server.Server, articles []Article) (string, error) {
+	request := struct {
+		Articles []Article `json:"articles"`
+	}{articles}
+	result := mediaIDRequest{}
+	err := svr.Client().PostJSONContext(ctx, addNewsPath, nil, &request, &result)
+	return result.MediaID, err
+}
+
+// UpdateNews 修改永久图文素材中第index篇文章，index从0开始
+func UpdateNews(svr *server.Server, mediaID string, index int, article *Article) error {
+	return UpdateNewsContext(context.Background(), svr, mediaID, index, article)
+}
+
+// UpdateNewsContext 修改永久图文素材中第index篇文章，index从0开始
+func UpdateNewsContext(ctx context.Context, svr *server.Server, mediaID string, index int, article *Article) error {
+	request := struct {
+		MediaID  string   `json:"media_id"`
+		Index    int      `json:"index"`
+		Articles *Article `json:"articles"`
+	}{mediaID, index, article}
+	return svr.Client().PostJSONContext(ctx, updateNewsPath, nil, &request, nil)
+}
+
+// GetMaterial 下载图片、语音、缩略图永久素材写入w
+func GetMaterial(svr *server.Server, mediaID string, w io.Writer) (http.Header, error) {
+	return GetMaterialContext(context.Background(), svr, mediaID, w)
+}
+
+// GetMaterialContext 下载图片、语音、缩略图永久素材写入w
+func GetMaterialContext(ctx context.Context, svr *server.Server, mediaID string, w io.Writer) (http.Header, error) {
+	return svr.Client().DownloadContext(ctx, getMaterialPath, nil, &mediaIDRequest{mediaID}, w)
+}
+
+// GetNews 获取永久图文素材
+func GetNews(svr *server.Server, mediaID string) ([]Article, error) {
+	return GetNewsContext(context.Background(), svr, mediaID)
+}
+
+// GetNewsContext 获取永久图文素材
+func GetNewsContext(ctx context.Context, svr *server.Server, mediaID string) ([]Article, error) {
+	result := struct {
+		NewsItem []Article `json:"news_item"`
+	}{}
+	err := svr.Client().PostJSONContext(ctx, getMaterialPath, nil, &mediaIDRequest{mediaID}, &result)
+	return result.NewsItem, err
+}
+
+// GetVideo 获取永久视频素材信息
+func GetVideo(svr *server.Server, mediaID string) (*Video, error) {
+	return GetVideoContext(context.Background(), svr, mediaID)
+}
+
+// GetVideoContext 获取永久视频素材信息
+func GetVideoContext(ctx context.Context, svr *server.Server, mediaID string) (*Video, error) {
+	result := new(Video)
+	err := svr.Client().PostJSONContext(ctx, getMaterialPath, nil, &mediaIDRequest{mediaID}, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteMaterial 删除永久素材
+func DeleteMaterial(svr *server.Server, mediaID string) error {
+	return DeleteMaterialContext(context.Background(), svr, mediaID)
+}
+
+// DeleteMaterialContext 删除永久素材
+func DeleteMaterialContext(ctx context.Context, svr *server.Server, mediaID string) error {
+	return svr.Client().PostJSONContext(ctx, deleteMaterialPath, nil, &mediaIDRequest{mediaID}, nil)
+}
+
+// GetMaterialCount 获取永久素材总数
+func GetMaterialCount(svr *server.Server) (*MaterialCount, error) {
+	return GetMaterialCountContext(context.Background(), svr)
+}
+
+// GetMaterialCountContext 获取永久素材总数
+func GetMaterialCountContext(ctx context.Context, svr *server.Server) (*MaterialCount, error) {
+	result := new(MaterialCount)
+	err := svr.Client().GetContext(ctx, materialCountPath, nil, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BatchGetMaterial 分页获取永久素材列表，count取值1到20
+func BatchGetMaterial(svr *server.Server, mediaType Type, offset int, count int) (*MaterialList, error) {
+	return BatchGetMaterialContext(context.Background(), svr, mediaType, offset, count)
+}
+
+// BatchGetMaterialContext 分页获取永久素材列表，count取值1到20
+func BatchGetMaterialContext(ctx context.Context, svr *server.Server, mediaType Type, offset int, count int) (*MaterialList, error) {
+	request := struct {
+		Type   Type `json:"type"`
+		Offset int  `json:"offset"`
+		Count  int  `json:"count"`
+	}{mediaType, offset, count}
+	result := new(MaterialList)
+	err := svr.Client().PostJSONContext(ctx, batchGetPath, nil, &request, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
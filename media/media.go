@@ -0,0 +1,95 @@
+package media
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+// Type 素材类型
+type Type string
+
+// 素材类型枚举
+const (
+	ImageType Type = "image"
+	VoiceType Type = "voice"
+	VideoType Type = "video"
+	ThumbType Type = "thumb"
+	NewsType  Type = "news"
+)
+
+const (
+	uploadTempPath  = "/cgi-bin/media/upload"
+	getTempPath     = "/cgi-bin/media/get"
+	getHDVoicePath  = "/cgi-bin/media/get/jssdk"
+	uploadImagePath = "/cgi-bin/media/uploadimg"
+)
+
+// mediaFieldName 上传文件的表单字段名
+const mediaFieldName = "media"
+
+// TempMedia 临时素材上传结果
+type TempMedia struct {
+	Type         Type   `json:"type"`
+	MediaID      string `json:"media_id"`
+	ThumbMediaID string `json:"thumb_media_id"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+// UploadTemp 上传临时素材，有效期3天
+func UploadTemp(svr *server.Server, mediaType Type, fileName string, reader io.Reader) (*TempMedia, error) {
+	return UploadTempContext(context.Background(), svr, mediaType, fileName, reader)
+}
+
+// UploadTempContext 上传临时素材，有效期3天
+func UploadTempContext(ctx context.Context, svr *server.Server, mediaType Type, fileName string, reader io.Reader) (*TempMedia, error) {
+	params := url.Values{}
+	params.Set("type", string(mediaType))
+	result := new(TempMedia)
+	err := svr.Client().UploadContext(ctx, uploadTempPath, params, mediaFieldName, fileName, reader, nil, result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetTemp 下载临时素材写入w，视频素材写入的是包含video_url的JSON
+func GetTemp(svr *server.Server, mediaID string, w io.Writer) (http.Header, error) {
+	return GetTempContext(context.Background(), svr, mediaID, w)
+}
+
+// GetTempContext 下载临时素材写入w，视频素材写入的是包含video_url的JSON
+func GetTempContext(ctx context.Context, svr *server.Server, mediaID string, w io.Writer) (http.Header, error) {
+	params := url.Values{}
+	params.Set("media_id", mediaID)
+	return svr.Client().DownloadContext(ctx, getTempPath, params, nil, w)
+}
+
+// GetHDVoice 下载JSSDK上传的高清语音素材写入w，格式为speex
+func GetHDVoice(svr *server.Server, mediaID string, w io.Writer) (http.Header, error) {
+	return GetHDVoiceContext(context.Background(), svr, mediaID, w)
+}
+
+// GetHDVoiceContext 下载JSSDK上传的高清语音素材写入w，格式为speex
+func GetHDVoiceContext(ctx context.Context, svr *server.Server, mediaID string, w io.Writer) (http.Header, error) {
+	params := url.Values{}
+	params.Set("media_id", mediaID)
+	return svr.Client().DownloadContext(ctx, getHDVoicePath, params, nil, w)
+}
+
+// UploadImage 上传图文消息内的图片，返回图片URL，不占用素材库数量
+func UploadImage(svr *server.Server, fileName string, reader io.Reader) (string, error) {
+	return UploadImageContext(context.Background(), svr, fileName, reader)
+}
+
+// UploadImageContext 上传图文消息内的图片，返回图片URL，不占用素材库数量
+func UploadImageContext(ctx context.Context, svr *server.Server, fileName string, reader io.Reader) (string, error) {
+	result := struct {
+		URL string `json:"url"`
+	}{}
+	err := svr.Client().UploadContext(ctx, uploadImagePath, nil, mediaFieldName, fileName, reader, nil, &result)
+	return result.URL, err
+}
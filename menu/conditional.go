@@ -0,0 +1,176 @@
+package menu
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const (
+	addConditionalPath    = "/cgi-bin/menu/addconditional"
+	deleteConditionalPath = "/cgi-bin/menu/delconditional"
+	tryMatchPath          = "/cgi-bin/menu/trymatch"
+)
+
+// 个性化菜单性别匹配枚举
+const (
+	SexMale   = "1"
+	SexFemale = "2"
+)
+
+// 个性化菜单客户端版本匹配枚举
+const (
+	PlatformIOS     = "1"
+	PlatformAndroid = "2"
+	PlatformOthers  = "3"
+)
+
+// MatchRule 个性化菜单匹配规则，为空的字段不参与匹配
+type MatchRule struct {
+	TagID              string `json:"tag_id,omitempty" yaml:"tag_id,omitempty"`
+	Sex                string `json:"sex,omitempty" yaml:"sex,omitempty"`
+	Country            string `json:"country,omitempty" yaml:"country,omitempty"`
+	Province           string `json:"province,omitempty" yaml:"province,omitempty"`
+	City               string `json:"city,omitempty" yaml:"city,omitempty"`
+	ClientPlatformType string `json:"client_platform_type,omitempty" yaml:"client_platform_type,omitempty"`
+	Language           string `json:"language,omitempty" yaml:"language,omitempty"`
+}
+
+// jsonMenuID 菜单ID，查询接口返回数字，创建接口返回字符串
+type jsonMenuID string
+
+func (id *jsonMenuID) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err == nil {
+		*id = jsonMenuID(number.String())
+		return nil
+	}
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return err
+	}
+	*id = jsonMenuID(str)
+	return nil
+}
+
+type jsonConditionalMenuResult struct {
+	ConditionalMenu []jsonButtons `json:"conditionalmenu"`
+}
+
+// SetMatchRule 设置个性化菜单匹配规则
+func (mm *MainMenu) SetMatchRule(rule *MatchRule) {
+	mm.matchRule = rule
+}
+
+// MatchRule 返回个性化菜单匹配规则，默认菜单返回nil
+func (mm *MainMenu) MatchRule() *MatchRule {
+	return mm.matchRule
+}
+
+// MenuID 返回菜单ID，个性化菜单创建成功或查询后才有值
+func (mm *MainMenu) MenuID() string {
+	return mm.menuID
+}
+
+// AddConditionalMenu 创建个性化菜单，成功后返回并记录菜单ID
+func AddConditionalMenu(menu *MainMenu) (string, error) {
+	return AddConditionalMenuContext(context.Background(), menu)
+}
+
+// AddConditionalMenuContext 创建个性化菜单，成功后返回并记录菜单ID
+func AddConditionalMenuContext(ctx context.Context, menu *MainMenu) (string, error) {
+	if err := menu.Validate(); err != nil {
+		return "", err
+	}
+	jsonBtns := menu.getJSONButtons()
+	jsonBtns.MatchRule = menu.matchRule
+	result := struct {
+		MenuID jsonMenuID `json:"menuid"`
+	}{}
+	if err := menu.client().PostJSONContext(ctx, addConditionalPath, nil, &jsonBtns, &result); err != nil {
+		return "", err
+	}
+	menu.menuID = string(result.MenuID)
+	return menu.menuID, nil
+}
+
+// DeleteConditionalMenu 按菜单ID删除个性化菜单
+func DeleteConditionalMenu(svr *server.Server, menuID string) error {
+	return DeleteConditionalMenuContext(context.Background(), svr, menuID)
+}
+
+// DeleteConditionalMenuContext 按菜单ID删除个性化菜单
+func DeleteConditionalMenuContext(ctx context.Context, svr *server.Server, menuID string) error {
+	return deleteConditionalMenu(ctx, svr.Client(), menuID)
+}
+
+// Delete 删除个性化菜单
+func (mm *MainMenu) Delete() error {
+	return mm.DeleteContext(context.Background())
+}
+
+// DeleteContext 删除个性化菜单
+func (mm *MainMenu) DeleteContext(ctx context.Context) error {
+	return deleteConditionalMenu(ctx, mm.client(), mm.menuID)
+}
+
+func deleteConditionalMenu(ctx context.Context, client *server.APIClient, menuID string) error {
+	request := struct {
+		MenuID string `json:"menuid"`
+	}{menuID}
+	return client.PostJSONContext(ctx, deleteConditionalPath, nil, &request, nil)
+}
+
+// GetConditionalMenus 查询全部个性化菜单
+func GetConditionalMenus(svr *server.Server) ([]*MainMenu, error) {
+	return GetConditionalMenusContext(context.Background(), svr)
+}
+
+// GetConditionalMenusContext 查询全部个性化菜单
+func GetConditionalMenusContext(ctx context.Context, svr *server.Server) ([]*MainMenu, error) {
+	result := jsonConditionalMenuResult{}
+	if err := svr.Client().GetContext(ctx, getMenuPath, nil, &result); err != nil {
+		// 没有菜单时返回46003
+		if server.IsErrCode(err, errCodeMenuNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var menus []*MainMenu
+	for index := 0; index < len(result.ConditionalMenu); index++ {
+		menus = append(menus, newMenuFromJSON(svr, &result.ConditionalMenu[index]))
+	}
+	return menus, nil
+}
+
+// TryMatch 测试个性化菜单匹配结果，userID为粉丝的OpenID或微信号
+func TryMatch(svr *server.Server, userID string) (*MainMenu, error) {
+	return TryMatchContext(context.Background(), svr, userID)
+}
+
+// TryMatchContext 测试个性化菜单匹配结果，userID为粉丝的OpenID或微信号
+func TryMatchContext(ctx context.Context, svr *server.Server, userID string) (*MainMenu, error) {
+	request := struct {
+		UserID string `json:"user_id"`
+	}{userID}
+	result := struct {
+		jsonButtons
+		Menu *jsonButtons `json:"menu"`
+	}{}
+	if err := svr.Client().PostJSONContext(ctx, tryMatchPath, nil, &request, &result); err != nil {
+		return nil, err
+	}
+	if result.Menu != nil {
+		return newMenuFromJSON(svr, result.Menu), nil
+	}
+	return newMenuFromJSON(svr, &result.jsonButtons), nil
+}
+
+// NewTagMatchRule 创建按用户标签匹配的规则
+func NewTagMatchRule(tagID int) *MatchRule {
+	rule := new(MatchRule)
+	rule.TagID = strconv.Itoa(tagID)
+	return rule
+}
@@ -0,0 +1,194 @@
+package menu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"coding.net/cherrysd/wxserver/server"
+	"gopkg.in/yaml.v2"
+)
+
+// Difference 本地菜单与线上菜单的一处差异，Path形如button[0].sub_button[1].url
+type Difference struct {
+	Path   string
+	Local  string
+	Remote string
+}
+
+func (d Difference) String() string {
+	return fmt.Sprintf("%s: %q -> %q", d.Path, d.Remote, d.Local)
+}
+
+// LoadJSON 从与微信接口格式相同的JSON文档加载菜单
+func LoadJSON(svr *server.Server, content []byte) (*MainMenu, error) {
+	doc := jsonButtons{}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	return newMenuFromJSON(svr, &doc), nil
+}
+
+// LoadYAML 从与微信接口格式相同的YAML文档加载菜单
+func LoadYAML(svr *server.Server, content []byte) (*MainMenu, error) {
+	doc := jsonButtons{}
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+	return newMenuFromJSON(svr, &doc), nil
+}
+
+// LoadFile 从文件加载菜单，扩展名为.yaml或.yml时按YAML解析，否则按JSON解析
+func LoadFile(svr *server.Server, path string) (*MainMenu, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return LoadYAML(svr, content)
+	}
+	return LoadJSON(svr, content)
+}
+
+// ToJSON 导出为与微信接口格式相同的JSON文档
+func (mm *MainMenu) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(mm.document(), "", "  ")
+}
+
+// ToYAML 导出为与微信接口格式相同的YAML文档
+func (mm *MainMenu) ToYAML() ([]byte, error) {
+	return yaml.Marshal(mm.document())
+}
+
+func (mm *MainMenu) document() jsonButtons {
+	doc := mm.getJSONButtons()
+	doc.MatchRule = mm.matchRule
+	return doc
+}
+
+// Diff 比较本地菜单与另一个菜单，没有差异时返回nil
+func Diff(local *MainMenu, remote *MainMenu) []Difference {
+	localBtns := local.getJSONButtons().Button
+	remoteBtns := remote.getJSONButtons().Button
+	// 个性化菜单的匹配规则也参与比较
+	diffs := diffFields("matchrule", matchRuleFieldNames, matchRuleFields(local.matchRule), matchRuleFields(remote.matchRule))
+	for index := 0; index < len(localBtns) || index < len(remoteBtns); index++ {
+		path := fmt.Sprintf("button[%d]", index)
+		if index >= len(remoteBtns) {
+			diffs = append(diffs, Difference{Path: path, Local: localBtns[index].Name})
+			continue
+		}
+		if index >= len(localBtns) {
+			diffs = append(diffs, Difference{Path: path, Remote: remoteBtns[index].Name})
+			continue
+		}
+
+		localBtn := localBtns[index]
+		remoteBtn := remoteBtns[index]
+		diffs = append(diffs, diffButton(path, buttonFields(&localBtn), buttonFields(&remoteBtn))...)
+		for i := 0; i < len(localBtn.SubButton) || i < len(remoteBtn.SubButton); i++ {
+			subPath := fmt.Sprintf("%s.sub_button[%d]", path, i)
+			if i >= len(remoteBtn.SubButton) {
+				diffs = append(diffs, Difference{Path: subPath, Local: localBtn.SubButton[i].Name})
+				continue
+			}
+			if i >= len(localBtn.SubButton) {
+				diffs = append(diffs, Difference{Path: subPath, Remote: remoteBtn.SubButton[i].Name})
+				continue
+			}
+			diffs = append(diffs, diffButton(subPath, subButtonFields(&localBtn.SubButton[i]), subButtonFields(&remoteBtn.SubButton[i]))...)
+		}
+	}
+	return diffs
+}
+
+// DiffLive 比较本地菜单与线上对应的菜单，个性化菜单按菜单ID或匹配规则查找线上的个性化菜单，否则与默认菜单比较
+func DiffLive(local *MainMenu) ([]Difference, error) {
+	return DiffLiveContext(context.Background(), local)
+}
+
+// DiffLiveContext 比较本地菜单与线上对应的菜单，个性化菜单按菜单ID或匹配规则查找线上的个性化菜单，否则与默认菜单比较
+func DiffLiveContext(ctx context.Context, local *MainMenu) ([]Difference, error) {
+	if local.dbServer == nil {
+		return nil, ErrNoServer
+	}
+	if local.matchRule != nil || local.menuID != "" {
+		remote, err := findConditionalMenu(ctx, local)
+		if err != nil {
+			return nil, err
+		}
+		return Diff(local, remote), nil
+	}
+
+	remote, err := GetMenuContext(ctx, local.dbServer)
+	if err != nil {
+		// 线上没有菜单时与空菜单比较
+		if !server.IsErrCode(err, errCodeMenuNotExist) {
+			return nil, err
+		}
+		remote = NewMenu(local.dbServer)
+	}
+	return Diff(local, remote), nil
+}
+
+// findConditionalMenu 查找与本地菜单对应的线上个性化菜单，优先按菜单ID匹配，找不到时返回空菜单
+func findConditionalMenu(ctx context.Context, local *MainMenu) (*MainMenu, error) {
+	menus, err := GetConditionalMenusContext(ctx, local.dbServer)
+	if err != nil {
+		return nil, err
+	}
+	if local.menuID != "" {
+		for _, menu := range menus {
+			if menu.menuID == local.menuID {
+				return menu, nil
+			}
+		}
+	}
+	if local.matchRule != nil {
+		for _, menu := range menus {
+			if menu.matchRule != nil && *menu.matchRule == *local.matchRule {
+				return menu, nil
+			}
+		}
+	}
+	return NewMenu(local.dbServer), nil
+}
+
+// buttonFieldNames 参与比较的按钮字段，与buttonFields返回值顺序一致
+var buttonFieldNames = []string{"name", "type", "key", "url", "media_id", "appid", "pagepath"}
+
+func buttonFields(btn *jsonButton) []string {
+	return []string{btn.Name, string(btn.ButtonType), btn.Key, btn.URL, btn.MediaID, btn.AppID, btn.PagePath}
+}
+
+func subButtonFields(btn *jsonSubButton) []string {
+	return []string{btn.Name, string(btn.ButtonType), btn.Key, btn.URL, btn.MediaID, btn.AppID, btn.PagePath}
+}
+
+// matchRuleFieldNames 参与比较的匹配规则字段，与matchRuleFields返回值顺序一致
+var matchRuleFieldNames = []string{"tag_id", "sex", "country", "province", "city", "client_platform_type", "language"}
+
+func matchRuleFields(rule *MatchRule) []string {
+	if rule == nil {
+		rule = &MatchRule{}
+	}
+	return []string{rule.TagID, rule.Sex, rule.Country, rule.Province, rule.City, rule.ClientPlatformType, rule.Language}
+}
+
+func diffButton(path string, local []string, remote []string) []Difference {
+	return diffFields(path, buttonFieldNames, local, remote)
+}
+
+func diffFields(path string, names []string, local []string, remote []string) []Difference {
+	var diffs []Difference
+	for index := 0; index < len(names); index++ {
+		if local[index] != remote[index] {
+			diffs = append(diffs, Difference{Path: path + "." + names[index], Local: local[index], Remote: remote[index]})
+		}
+	}
+	return diffs
+}
@@ -0,0 +1,74 @@
+package menu
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const liveMenuJSON = `{
+	"menu": {"button": [{"type": "click", "name": "default", "key": "DEFAULT"}], "menuid": 100},
+	"conditionalmenu": [
+		{"button": [{"type": "click", "name": "vip", "key": "VIP"}], "matchrule": {"tag_id": "2"}, "menuid": 200},
+		{"button": [{"type": "click", "name": "english", "key": "EN"}], "matchrule": {"language": "en"}, "menuid": 300}
+	]
+}`
+
+func newLiveServer(t *testing.T) *server.Server {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/token":
+			fmt.Fprint(w, `{"access_token":"TOKEN","expires_in":7200}`)
+		case getMenuPath:
+			fmt.Fprint(w, liveMenuJSON)
+		}
+	}))
+	t.Cleanup(api.Close)
+	svr := server.NewServer("token")
+	svr.Client().SetBaseURL(api.URL)
+	svr.TokenManager().SetClient(svr.Client())
+	svr.SetAppInfo("appid", "secret")
+	return svr
+}
+
+func TestDiffLive(t *testing.T) {
+	svr := newLiveServer(t)
+	cases := []struct {
+		name  string
+		doc   string
+		diffs int
+	}{
+		{"default", `{"button": [{"type": "click", "name": "default", "key": "DEFAULT"}]}`, 0},
+		{"conditional by match rule", `{"button": [{"type": "click", "name": "vip", "key": "VIP"}], "matchrule": {"tag_id": "2"}}`, 0},
+		{"conditional by menu id", `{"button": [{"type": "click", "name": "english", "key": "EN"}], "matchrule": {"language": "en"}, "menuid": "300"}`, 0},
+		{"conditional changed", `{"button": [{"type": "click", "name": "vip", "key": "VIP2"}], "matchrule": {"tag_id": "2"}}`, 1},
+		{"conditional not live", `{"button": [{"type": "click", "name": "vip", "key": "VIP"}], "matchrule": {"tag_id": "3"}}`, 2},
+	}
+	for _, tc := range cases {
+		local, err := LoadJSON(svr, []byte(tc.doc))
+		if err != nil {
+			t.Fatalf("%s: LoadJSON: %v", tc.name, err)
+		}
+		diffs, err := DiffLive(local)
+		if err != nil {
+			t.Errorf("%s: DiffLive: %v", tc.name, err)
+			continue
+		}
+		if len(diffs) != tc.diffs {
+			t.Errorf("%s: DiffLive = %v, want %d differences", tc.name, diffs, tc.diffs)
+		}
+	}
+}
+
+func TestDiffLiveNoServer(t *testing.T) {
+	local, err := LoadJSON(nil, []byte(`{"button": [{"type": "click", "name": "a", "key": "A"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DiffLive(local); err != ErrNoServer {
+		t.Errorf("DiffLive() err = %v, want %v", err, ErrNoServer)
+	}
+}
@@ -0,0 +1,59 @@
+package menu
+
+import (
+	"errors"
+	"fmt"
+
+	"coding.net/cherrysd/wxserver/message"
+	"coding.net/cherrysd/wxserver/server"
+)
+
+// ErrNoServer 菜单未关联服务实例，无法注册事件处理器或查询线上菜单
+var ErrNoServer = errors.New("menu: menu has no server")
+
+// HandleKey 为菜单中指定Key的按钮设置事件处理器，菜单中没有该Key时返回错误
+//
+// 设置后需调用RegisterHandles注册到服务实例
+func (mm *MainMenu) HandleKey(key string, handle func(*server.Context, *message.MenuEvent)) error {
+	found := false
+	for index := 0; index < len(mm.buttons); index++ {
+		if len(mm.buttons[index].SubButton) == 0 && mm.buttons[index].Key == key {
+			mm.buttons[index].Handle = handle
+			found = true
+		}
+		for i := 0; i < len(mm.buttons[index].SubButton); i++ {
+			if mm.buttons[index].SubButton[i].Key == key {
+				mm.buttons[index].SubButton[i].Handle = handle
+				found = true
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("menu: no button with key %q", key)
+	}
+	return nil
+}
+
+// RegisterHandles 将按钮上的事件处理器按Key注册到菜单所属的服务实例
+//
+// 点击、扫码、发图、发送位置事件会路由到对应按钮的处理器，菜单无需重新创建，每次启动服务时调用即可
+//
+// 菜单未关联服务实例时返回ErrNoServer
+func (mm *MainMenu) RegisterHandles() error {
+	if mm.dbServer == nil {
+		return ErrNoServer
+	}
+	for index := 0; index < len(mm.buttons); index++ {
+		btn := mm.buttons[index]
+		if len(btn.SubButton) == 0 && btn.Handle != nil && btn.Key != "" {
+			mm.dbServer.OnMenuKey(btn.Key, btn.Handle)
+		}
+		for i := 0; i < len(btn.SubButton); i++ {
+			subBtn := btn.SubButton[i]
+			if subBtn.Handle != nil && subBtn.Key != "" {
+				mm.dbServer.OnMenuKey(subBtn.Key, subBtn.Handle)
+			}
+		}
+	}
+	return nil
+}
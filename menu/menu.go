@@ -1,20 +1,18 @@
 package menu
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
+	"net/url"
 
+	"coding.net/cherrysd/wxserver/message"
 	"coding.net/cherrysd/wxserver/server"
 )
 
 // Type 菜单类型
 type Type string
 
-const jsonContentType = "application/json;charset=utf-8"
-const createMenuURL = "https://api.weixin.qq.com/cgi-bin/menu/create?access_token=%s"
+const createMenuPath = "/cgi-bin/menu/create"
 
 // 菜单类型枚举
 const (
@@ -32,28 +30,30 @@ const (
 )
 
 type jsonButtons struct {
-	Button []jsonButton `json:"button"`
+	Button    []jsonButton `json:"button" yaml:"button"`
+	MatchRule *MatchRule   `json:"matchrule,omitempty" yaml:"matchrule,omitempty"`
+	MenuID    jsonMenuID   `json:"menuid,omitempty" yaml:"menuid,omitempty"`
 }
 
 type jsonButton struct {
-	Name       string          `json:"name,omitempty"`
-	ButtonType Type            `json:"type,omitempty"`
-	Key        string          `json:"key,omitempty"`
-	URL        string          `json:"url,omitempty"`
-	MediaID    string          `json:"media_id,omitempty"`
-	AppID      string          `json:"appid,omitempty"`
-	PagePath   string          `json:"pagepath,omitempty"`
-	SubButton  []jsonSubButton `json:"sub_button,omitempty"`
+	Name       string          `json:"name,omitempty" yaml:"name,omitempty"`
+	ButtonType Type            `json:"type,omitempty" yaml:"type,omitempty"`
+	Key        string          `json:"key,omitempty" yaml:"key,omitempty"`
+	URL        string          `json:"url,omitempty" yaml:"url,omitempty"`
+	MediaID    string          `json:"media_id,omitempty" yaml:"media_id,omitempty"`
+	AppID      string          `json:"appid,omitempty" yaml:"appid,omitempty"`
+	PagePath   string          `json:"pagepath,omitempty" yaml:"pagepath,omitempty"`
+	SubButton  []jsonSubButton `json:"sub_button,omitempty" yaml:"sub_button,omitempty"`
 }
 
 type jsonSubButton struct {
-	Name       string `json:"name,omitempty"`
-	ButtonType Type   `json:"type,omitempty"`
-	Key        string `json:"key,omitempty"`
-	URL        string `json:"url,omitempty"`
-	MediaID    string `json:"media_id,omitempty"`
-	AppID      string `json:"appid,omitempty"`
-	PagePath   string `json:"pagepath,omitempty"`
+	Name       string `json:"name,omitempty" yaml:"name,omitempty"`
+	ButtonType Type   `json:"type,omitempty" yaml:"type,omitempty"`
+	Key        string `json:"key,omitempty" yaml:"key,omitempty"`
+	URL        string `json:"url,omitempty" yaml:"url,omitempty"`
+	MediaID    string `json:"media_id,omitempty" yaml:"media_id,omitempty"`
+	AppID      string `json:"appid,omitempty" yaml:"appid,omitempty"`
+	PagePath   string `json:"pagepath,omitempty" yaml:"pagepath,omitempty"`
 }
 
 // LevelButton 一级按钮(弹出二级菜单)
@@ -62,7 +62,9 @@ type LevelButton struct {
 	SubButton []FunctionButton
 }
 
-// FunctionButton 功能按钮
+// FunctionButton 功能按钮，Value与NewsInfo为官网设置的菜单内容，仅查询时返回
+//
+// Handle不为nil时，RegisterHandles会按Key将其注册为该按钮的事件处理器
 type FunctionButton struct {
 	Name       string
 	ButtonType Type
@@ -71,6 +73,9 @@ type FunctionButton struct {
 	MediaID    string
 	AppID      string
 	PagePath   string
+	Value      string
+	NewsInfo   []NewsInfo
+	Handle     func(*server.Context, *message.MenuEvent)
 }
 
 type innerButton struct {
@@ -81,6 +86,9 @@ type innerButton struct {
 	MediaID    string
 	AppID      string
 	PagePath   string
+	Value      string
+	NewsInfo   []NewsInfo
+	Handle     func(*server.Context, *message.MenuEvent)
 	SubButton  []innerSubButton
 }
 
@@ -92,84 +100,45 @@ type innerSubButton struct {
 	MediaID    string
 	AppID      string
 	PagePath   string
+	Value      string
+	NewsInfo   []NewsInfo
+	Handle     func(*server.Context, *message.MenuEvent)
 }
 
 // MainMenu 菜单实例
 type MainMenu struct {
-	buttons  []innerButton
-	dbServer *server.Server
-}
-
-// Error 菜单接口调用结果JSON
-type Error struct {
-	ErrCode int    `json:"errcode"`
-	ErrMsg  string `json:"errmsg"`
+	buttons   []innerButton
+	dbServer  *server.Server
+	matchRule *MatchRule
+	menuID    string
 }
 
 // CreateMenu 创建菜单
-func CreateMenu(menu *MainMenu) bool {
-	jsonContent, err := menu.formatJSON()
-	if err != nil {
-		return false
-	}
-
-	body := bytes.NewBuffer(jsonContent)
-	menuURL := fmt.Sprintf(createMenuURL, menu.dbServer.GetAccessToken())
-	response, postErr := http.Post(menuURL, jsonContentType, body)
-	if postErr != nil {
-		return false
-	}
-
-	result, responseError := ioutil.ReadAll(response.Body)
-	if responseError != nil {
-		return false
-	}
+func CreateMenu(menu *MainMenu) error {
+	return CreateMenuContext(context.Background(), menu)
+}
 
-	resultJSON := Error{}
-	jsonError := json.Unmarshal(result, &resultJSON)
-	if jsonError != nil {
-		return false
+// CreateMenuContext 创建菜单
+func CreateMenuContext(ctx context.Context, menu *MainMenu) error {
+	if err := menu.Validate(); err != nil {
+		return err
 	}
-
-	if resultJSON.ErrCode != 0 {
-		fmt.Println(resultJSON.ErrMsg)
-		return false
-	}
-
-	return true
+	return menu.dbServer.Client().PostJSONContext(ctx, createMenuPath, nil, menu.getJSONButtons(), nil)
 }
 
 // CreateMenuWithToken 传入token创建菜单
-func CreateMenuWithToken(menu *MainMenu, accessToken string) bool {
-	jsonContent, err := menu.formatJSON()
-	if err != nil {
-		return false
-	}
-
-	body := bytes.NewBuffer(jsonContent)
-	menuURL := fmt.Sprintf(createMenuURL, accessToken)
-	response, postErr := http.Post(menuURL, jsonContentType, body)
-	if postErr != nil {
-		return false
-	}
-
-	result, responseError := ioutil.ReadAll(response.Body)
-	if responseError != nil {
-		return false
-	}
-
-	resultJSON := Error{}
-	jsonError := json.Unmarshal(result, &resultJSON)
-	if jsonError != nil {
-		return false
-	}
+func CreateMenuWithToken(menu *MainMenu, accessToken string) error {
+	return CreateMenuWithTokenContext(context.Background(), menu, accessToken)
+}
 
-	if resultJSON.ErrCode != 0 {
-		fmt.Println(resultJSON.ErrMsg)
-		return false
+// CreateMenuWithTokenContext 传入token创建菜单
+func CreateMenuWithTokenContext(ctx context.Context, menu *MainMenu, accessToken string) error {
+	if err := menu.Validate(); err != nil {
+		return err
 	}
-
-	return true
+	params := url.Values{}
+	params.Set("access_token", accessToken)
+	return menu.client().PostJSONContext(ctx, createMenuPath, params, menu.getJSONButtons(), nil)
 }
 
 // NewMenu 新建菜单实例
@@ -179,9 +148,17 @@ func NewMenu(svr *server.Server) *MainMenu {
 	return newSvr
 }
 
+// client 返回菜单所属实例的接口客户端，未绑定实例时使用默认客户端
+func (mm *MainMenu) client() *server.APIClient {
+	if mm.dbServer == nil {
+		return server.DefaultClient
+	}
+	return mm.dbServer.Client()
+}
+
 // AddFirstLevelButton 添加一级菜单按钮
 func (mm *MainMenu) AddFirstLevelButton(btn *LevelButton) bool {
-	if len(btn.SubButton) > 5 {
+	if len(mm.buttons) >= maxButtons || len(btn.SubButton) > maxSubButtons {
 		return false
 	}
 	innerBtn := innerButton{}
@@ -195,6 +172,9 @@ func (mm *MainMenu) AddFirstLevelButton(btn *LevelButton) bool {
 		subBtn.MediaID = btn.SubButton[index].MediaID
 		subBtn.AppID = btn.SubButton[index].AppID
 		subBtn.PagePath = btn.SubButton[index].PagePath
+		subBtn.Value = btn.SubButton[index].Value
+		subBtn.NewsInfo = btn.SubButton[index].NewsInfo
+		subBtn.Handle = btn.SubButton[index].Handle
 		innerBtn.SubButton = append(innerBtn.SubButton, subBtn)
 	}
 	mm.buttons = append(mm.buttons, innerBtn)
@@ -203,7 +183,7 @@ func (mm *MainMenu) AddFirstLevelButton(btn *LevelButton) bool {
 
 // AddFunctionButton 添加功能按钮
 func (mm *MainMenu) AddFunctionButton(btn *FunctionButton) bool {
-	if len(mm.buttons) > 2 {
+	if len(mm.buttons) >= maxButtons {
 		return false
 	}
 
@@ -215,6 +195,9 @@ func (mm *MainMenu) AddFunctionButton(btn *FunctionButton) bool {
 	innerBtn.MediaID = btn.MediaID
 	innerBtn.AppID = btn.AppID
 	innerBtn.PagePath = btn.PagePath
+	innerBtn.Value = btn.Value
+	innerBtn.NewsInfo = btn.NewsInfo
+	innerBtn.Handle = btn.Handle
 
 	mm.buttons = append(mm.buttons, innerBtn)
 	return true
@@ -222,7 +205,7 @@ func (mm *MainMenu) AddFunctionButton(btn *FunctionButton) bool {
 
 // AddFunctionButton 添加功能按钮
 func (menuBtn *LevelButton) AddFunctionButton(btn *FunctionButton) bool {
-	if len(menuBtn.SubButton) > 4 {
+	if len(menuBtn.SubButton) >= maxSubButtons {
 		return false
 	}
 	menuBtn.SubButton = append(menuBtn.SubButton, *btn)
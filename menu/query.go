@@ -0,0 +1,215 @@
+package menu
+
+import (
+	"context"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+// errCodeMenuNotExist 菜单不存在
+const errCodeMenuNotExist = 46003
+
+const (
+	getMenuPath         = "/cgi-bin/menu/get"
+	deleteMenuPath      = "/cgi-bin/menu/delete"
+	getSelfMenuInfoPath = "/cgi-bin/get_current_selfmenu_info"
+)
+
+// MenuButton 一级菜单项，Level与Function有且只有一个不为nil
+type MenuButton struct {
+	Level    *LevelButton
+	Function *FunctionButton
+}
+
+// SelfMenuInfo 当前生效的自定义菜单，包含在公众平台官网设置的菜单
+type SelfMenuInfo struct {
+	IsMenuOpen bool
+	Menu       *MainMenu
+}
+
+// NewsInfo 官网设置的图文消息菜单内容
+type NewsInfo struct {
+	Title      string `json:"title"`
+	Author     string `json:"author"`
+	Digest     string `json:"digest"`
+	ShowCover  int    `json:"show_cover"`
+	CoverURL   string `json:"cover_url"`
+	ContentURL string `json:"content_url"`
+	SourceURL  string `json:"source_url"`
+}
+
+type jsonMenuResult struct {
+	Menu jsonButtons `json:"menu"`
+}
+
+type jsonSelfMenuButton struct {
+	Name       string `json:"name"`
+	ButtonType Type   `json:"type"`
+	Key        string `json:"key"`
+	URL        string `json:"url"`
+	Value      string `json:"value"`
+	AppID      string `json:"appid"`
+	PagePath   string `json:"pagepath"`
+	NewsInfo   struct {
+		List []NewsInfo `json:"list"`
+	} `json:"news_info"`
+	SubButton struct {
+		List []jsonSelfMenuButton `json:"list"`
+	} `json:"sub_button"`
+}
+
+type jsonSelfMenuResult struct {
+	IsMenuOpen   int `json:"is_menu_open"`
+	SelfMenuInfo struct {
+		Button []jsonSelfMenuButton `json:"button"`
+	} `json:"selfmenu_info"`
+}
+
+// GetMenu 查询通过接口创建的默认菜单
+func GetMenu(svr *server.Server) (*MainMenu, error) {
+	return GetMenuContext(context.Background(), svr)
+}
+
+// GetMenuContext 查询通过接口创建的默认菜单
+func GetMenuContext(ctx context.Context, svr *server.Server) (*MainMenu, error) {
+	result := jsonMenuResult{}
+	if err := svr.Client().GetContext(ctx, getMenuPath, nil, &result); err != nil {
+		return nil, err
+	}
+	return newMenuFromJSON(svr, &result.Menu), nil
+}
+
+// DeleteMenu 删除全部菜单，包括个性化菜单
+func DeleteMenu(svr *server.Server) error {
+	return DeleteMenuContext(context.Background(), svr)
+}
+
+// DeleteMenuContext 删除全部菜单，包括个性化菜单
+func DeleteMenuContext(ctx context.Context, svr *server.Server) error {
+	return svr.Client().GetContext(ctx, deleteMenuPath, nil, nil)
+}
+
+// GetCurrentSelfMenuInfo 查询当前生效的菜单，官网设置的菜单内容在FunctionButton.Value与NewsInfo中
+func GetCurrentSelfMenuInfo(svr *server.Server) (*SelfMenuInfo, error) {
+	return GetCurrentSelfMenuInfoContext(context.Background(), svr)
+}
+
+// GetCurrentSelfMenuInfoContext 查询当前生效的菜单，官网设置的菜单内容在FunctionButton.Value与NewsInfo中
+func GetCurrentSelfMenuInfoContext(ctx context.Context, svr *server.Server) (*SelfMenuInfo, error) {
+	result := jsonSelfMenuResult{}
+	if err := svr.Client().GetContext(ctx, getSelfMenuInfoPath, nil, &result); err != nil {
+		return nil, err
+	}
+
+	info := new(SelfMenuInfo)
+	info.IsMenuOpen = result.IsMenuOpen == 1
+	info.Menu = NewMenu(svr)
+	for _, btn := range result.SelfMenuInfo.Button {
+		innerBtn := innerButton{}
+		innerBtn.Name = btn.Name
+		if len(btn.SubButton.List) > 0 {
+			for _, subBtn := range btn.SubButton.List {
+				innerBtn.SubButton = append(innerBtn.SubButton, innerSubButton(selfMenuFunctionButton(&subBtn)))
+			}
+		} else {
+			funcBtn := selfMenuFunctionButton(&btn)
+			innerBtn.ButtonType = funcBtn.ButtonType
+			innerBtn.Key = funcBtn.Key
+			innerBtn.URL = funcBtn.URL
+			innerBtn.MediaID = funcBtn.MediaID
+			innerBtn.AppID = funcBtn.AppID
+			innerBtn.PagePath = funcBtn.PagePath
+			innerBtn.Value = funcBtn.Value
+			innerBtn.NewsInfo = funcBtn.NewsInfo
+		}
+		info.Menu.buttons = append(info.Menu.buttons, innerBtn)
+	}
+	return info, nil
+}
+
+func selfMenuFunctionButton(btn *jsonSelfMenuButton) FunctionButton {
+	funcBtn := FunctionButton{}
+	funcBtn.Name = btn.Name
+	funcBtn.ButtonType = btn.ButtonType
+	funcBtn.Key = btn.Key
+	funcBtn.URL = btn.URL
+	funcBtn.AppID = btn.AppID
+	funcBtn.PagePath = btn.PagePath
+	funcBtn.NewsInfo = btn.NewsInfo.List
+	switch btn.ButtonType {
+	case MediaIDType, ViewLimitType:
+		funcBtn.MediaID = btn.Value
+	default:
+		funcBtn.Value = btn.Value
+	}
+	return funcBtn
+}
+
+// newMenuFromJSON 将接口返回的菜单JSON转为菜单实例
+func newMenuFromJSON(svr *server.Server, jsonBtns *jsonButtons) *MainMenu {
+	mm := NewMenu(svr)
+	mm.matchRule = jsonBtns.MatchRule
+	mm.menuID = string(jsonBtns.MenuID)
+	for index := 0; index < len(jsonBtns.Button); index++ {
+		jsonBtn := jsonBtns.Button[index]
+		innerBtn := innerButton{}
+		innerBtn.Name = jsonBtn.Name
+		if len(jsonBtn.SubButton) > 0 {
+			for i := 0; i < len(jsonBtn.SubButton); i++ {
+				subBtn := innerSubButton{}
+				subBtn.Name = jsonBtn.SubButton[i].Name
+				subBtn.ButtonType = jsonBtn.SubButton[i].ButtonType
+				subBtn.Key = jsonBtn.SubButton[i].Key
+				subBtn.URL = jsonBtn.SubButton[i].URL
+				subBtn.MediaID = jsonBtn.SubButton[i].MediaID
+				subBtn.AppID = jsonBtn.SubButton[i].AppID
+				subBtn.PagePath = jsonBtn.SubButton[i].PagePath
+				innerBtn.SubButton = append(innerBtn.SubButton, subBtn)
+			}
+		} else {
+			innerBtn.ButtonType = jsonBtn.ButtonType
+			innerBtn.Key = jsonBtn.Key
+			innerBtn.URL = jsonBtn.URL
+			innerBtn.MediaID = jsonBtn.MediaID
+			innerBtn.AppID = jsonBtn.AppID
+			innerBtn.PagePath = jsonBtn.PagePath
+		}
+		mm.buttons = append(mm.buttons, innerBtn)
+	}
+	return mm
+}
+
+// Buttons 返回菜单中的一级菜单项，修改返回值不影响菜单本身
+func (mm *MainMenu) Buttons() []MenuButton {
+	var buttons []MenuButton
+	for index := 0; index < len(mm.buttons); index++ {
+		innerBtn := mm.buttons[index]
+		if len(innerBtn.SubButton) > 0 {
+			levelBtn := new(LevelButton)
+			levelBtn.Name = innerBtn.Name
+			for i := 0; i < len(innerBtn.SubButton); i++ {
+				levelBtn.SubButton = append(levelBtn.SubButton, FunctionButton(innerBtn.SubButton[i]))
+			}
+			buttons = append(buttons, MenuButton{Level: levelBtn})
+		} else {
+			funcBtn := new(FunctionButton)
+			funcBtn.Name = innerBtn.Name
+			funcBtn.ButtonType = innerBtn.ButtonType
+			funcBtn.Key = innerBtn.Key
+			funcBtn.URL = innerBtn.URL
+			funcBtn.MediaID = innerBtn.MediaID
+			funcBtn.AppID = innerBtn.AppID
+			funcBtn.PagePath = innerBtn.PagePath
+			funcBtn.Value = innerBtn.Value
+			funcBtn.NewsInfo = innerBtn.NewsInfo
+			funcBtn.Handle = innerBtn.Handle
+			buttons = append(buttons, MenuButton{Function: funcBtn})
+		}
+	}
+	return buttons
+}
+
+// ClearButtons 清空菜单中的全部按钮，可配合Buttons修改后重新添加
+func (mm *MainMenu) ClearButtons() {
+	mm.buttons = nil
+}
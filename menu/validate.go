@@ -0,0 +1,124 @@
+package menu
+
+import (
+	"fmt"
+)
+
+// 菜单限制
+const (
+	maxButtons      = 3
+	maxSubButtons   = 5
+	maxNameBytes    = 16
+	maxSubNameBytes = 60
+	maxKeyBytes     = 128
+	maxURLBytes     = 1024
+	noIndex         = -1
+)
+
+// ValidationError 菜单校验错误，Index为-1时表示整个菜单，SubIndex为-1时表示一级按钮
+type ValidationError struct {
+	Index    int
+	SubIndex int
+	Name     string
+	Reason   string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Index == noIndex {
+		return fmt.Sprintf("menu: %s", e.Reason)
+	}
+	if e.SubIndex == noIndex {
+		return fmt.Sprintf("menu: button[%d] %q: %s", e.Index, e.Name, e.Reason)
+	}
+	return fmt.Sprintf("menu: button[%d].sub_button[%d] %q: %s", e.Index, e.SubIndex, e.Name, e.Reason)
+}
+
+// Validate 按微信限制校验菜单，返回第一个不合法的按钮
+func (mm *MainMenu) Validate() error {
+	if len(mm.buttons) == 0 {
+		return &ValidationError{Index: noIndex, SubIndex: noIndex, Reason: "menu has no button"}
+	}
+	if len(mm.buttons) > maxButtons {
+		return &ValidationError{Index: noIndex, SubIndex: noIndex, Reason: fmt.Sprintf("at most %d buttons, got %d", maxButtons, len(mm.buttons))}
+	}
+
+	for index := 0; index < len(mm.buttons); index++ {
+		btn := mm.buttons[index]
+		newError := func(subIndex int, name string, reason string) error {
+			return &ValidationError{Index: index, SubIndex: subIndex, Name: name, Reason: reason}
+		}
+
+		if btn.Name == "" {
+			return newError(noIndex, btn.Name, "name is required")
+		}
+		if len(btn.Name) > maxNameBytes {
+			return newError(noIndex, btn.Name, fmt.Sprintf("name exceeds %d bytes", maxNameBytes))
+		}
+
+		if len(btn.SubButton) == 0 {
+			funcBtn := FunctionButton{}
+			funcBtn.Name = btn.Name
+			funcBtn.ButtonType = btn.ButtonType
+			funcBtn.Key = btn.Key
+			funcBtn.URL = btn.URL
+			funcBtn.MediaID = btn.MediaID
+			funcBtn.AppID = btn.AppID
+			funcBtn.PagePath = btn.PagePath
+			if reason := validateFunctionButton(&funcBtn); reason != "" {
+				return newError(noIndex, btn.Name, reason)
+			}
+			continue
+		}
+
+		if len(btn.SubButton) > maxSubButtons {
+			return newError(noIndex, btn.Name, fmt.Sprintf("at most %d sub buttons, got %d", maxSubButtons, len(btn.SubButton)))
+		}
+		for i := 0; i < len(btn.SubButton); i++ {
+			subBtn := FunctionButton(btn.SubButton[i])
+			if subBtn.Name == "" {
+				return newError(i, subBtn.Name, "name is required")
+			}
+			if len(subBtn.Name) > maxSubNameBytes {
+				return newError(i, subBtn.Name, fmt.Sprintf("name exceeds %d bytes", maxSubNameBytes))
+			}
+			if reason := validateFunctionButton(&subBtn); reason != "" {
+				return newError(i, subBtn.Name, reason)
+			}
+		}
+	}
+	return nil
+}
+
+// validateFunctionButton 按按钮类型校验必填字段，合法时返回空字符串
+func validateFunctionButton(btn *FunctionButton) string {
+	if len(btn.Key) > maxKeyBytes {
+		return fmt.Sprintf("key exceeds %d bytes", maxKeyBytes)
+	}
+	if len(btn.URL) > maxURLBytes {
+		return fmt.Sprintf("url exceeds %d bytes", maxURLBytes)
+	}
+
+	switch btn.ButtonType {
+	case ClickType, ScancodePushType, ScancodeWatingMsgType, PicSysPhotoType, PicPhotoAlbumType, PicWXType, LocationSelectType:
+		if btn.Key == "" {
+			return fmt.Sprintf("key is required for %s", btn.ButtonType)
+		}
+	case ViewType:
+		if btn.URL == "" {
+			return "url is required for view"
+		}
+	case MiniProgramType:
+		if btn.AppID == "" || btn.PagePath == "" || btn.URL == "" {
+			return "appid, pagepath and url are required for miniprogram"
+		}
+	case MediaIDType, ViewLimitType:
+		if btn.MediaID == "" {
+			return fmt.Sprintf("media_id is required for %s", btn.ButtonType)
+		}
+	case "":
+		return "type is required"
+	default:
+		return fmt.Sprintf("unknown type %q", btn.ButtonType)
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package menu
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	click := func(name string) string {
+		return fmt.Sprintf(`{"type":"click","name":%q,"key":"k"}`, name)
+	}
+	buttons := func(btns ...string) string {
+		return `{"button":[` + strings.Join(btns, ",") + `]}`
+	}
+	level := func(name string, subs ...string) string {
+		return fmt.Sprintf(`{"name":%q,"sub_button":[%s]}`, name, strings.Join(subs, ","))
+	}
+
+	cases := []struct {
+		name     string
+		doc      string
+		index    int
+		subIndex int
+		reason   string
+	}{
+		{"valid", buttons(click("a"), level("b", click("b1"), `{"type":"view","name":"b2","url":"http://example.com"}`)), 0, 0, ""},
+		{"empty menu", buttons(), noIndex, noIndex, "no button"},
+		{"too many buttons", buttons(click("a"), click("b"), click("c"), click("d")), noIndex, noIndex, "at most 3 buttons"},
+		{"too many sub buttons", buttons(click("a"), level("b", click("1"), click("2"), click("3"), click("4"), click("5"), click("6"))), 1, noIndex, "at most 5 sub buttons"},
+		{"name required", buttons(click("a"), click("")), 1, noIndex, "name is required"},
+		{"name 16 bytes", buttons(click(strings.Repeat("a", 16))), 0, 0, ""},
+		{"name over 16 bytes", buttons(click("一二三四五六")), 0, noIndex, "name exceeds 16 bytes"},
+		{"sub name 60 bytes", buttons(level("a", click(strings.Repeat("a", 60)))), 0, 0, ""},
+		{"sub name over 60 bytes", buttons(level("a", click("b"), click(strings.Repeat("a", 61)))), 0, 1, "name exceeds 60 bytes"},
+		{"sub name required", buttons(level("a", click(""))), 0, 0, "name is required"},
+		{"key 128 bytes", buttons(fmt.Sprintf(`{"type":"click","name":"a","key":%q}`, strings.Repeat("k", 128))), 0, 0, ""},
+		{"key over 128 bytes", buttons(fmt.Sprintf(`{"type":"click","name":"a","key":%q}`, strings.Repeat("k", 129))), 0, noIndex, "key exceeds 128 bytes"},
+		{"click without key", buttons(`{"type":"click","name":"a"}`), 0, noIndex, "key is required for click"},
+		{"scancode without key", buttons(level("a", `{"type":"scancode_push","name":"b"}`)), 0, 0, "key is required for scancode_push"},
+		{"view without url", buttons(`{"type":"view","name":"a"}`), 0, noIndex, "url is required for view"},
+		{"miniprogram without pagepath", buttons(`{"type":"miniprogram","name":"a","appid":"wx","url":"http://example.com"}`), 0, noIndex, "appid, pagepath and url are required"},
+		{"media_id without media_id", buttons(`{"type":"media_id","name":"a"}`), 0, noIndex, "media_id is required for media_id"},
+		{"view_limited without media_id", buttons(`{"type":"view_limited","name":"a"}`), 0, noIndex, "media_id is required for view_limited"},
+		{"type required", buttons(`{"name":"a"}`, level("b")), 0, noIndex, "type is required"},
+		{"unknown type", buttons(click("a"), `{"type":"other","name":"b"}`), 1, noIndex, `unknown type "other"`},
+	}
+	for _, tc := range cases {
+		menu, err := LoadJSON(nil, []byte(tc.doc))
+		if err != nil {
+			t.Fatalf("%s: LoadJSON: %v", tc.name, err)
+		}
+		err = menu.Validate()
+		if tc.reason == "" {
+			if err != nil {
+				t.Errorf("%s: Validate() = %v, want nil", tc.name, err)
+			}
+			continue
+		}
+		validationErr, ok := err.(*ValidationError)
+		if !ok {
+			t.Errorf("%s: Validate() = %v, want *ValidationError", tc.name, err)
+			continue
+		}
+		if validationErr.Index != tc.index || validationErr.SubIndex != tc.subIndex || !strings.Contains(validationErr.Reason, tc.reason) {
+			t.Errorf("%s: Validate() = %+v, want index %d sub index %d reason %q", tc.name, *validationErr, tc.index, tc.subIndex, tc.reason)
+		}
+	}
+}
+
+func TestValidationErrorNamesButton(t *testing.T) {
+	menu, err := LoadJSON(nil, []byte(`{"button":[{"type":"click","name":"a","key":"k"},{"name":"more","sub_button":[{"type":"view","name":"help"}]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `menu: button[1].sub_button[0] "help": url is required for view`
+	if err := menu.Validate(); err == nil || err.Error() != want {
+		t.Errorf("Validate() = %v, want %s", err, want)
+	}
+}
@@ -1,6 +1,7 @@
 package message
 
 import (
+	"context"
 	"encoding/xml"
 	"fmt"
 	"log"
@@ -22,6 +23,9 @@ type responseArticlesMessage struct {
 	Content      []responseArticleMessage `xml:"Articles>item"`
 }
 
+// URLShortener 链接缩短函数，需在ctx到期前返回
+type URLShortener func(ctx context.Context, longURL string) (string, error)
+
 // Article 回复图文逻辑子项消息体
 type Article struct {
 	Title       string
@@ -84,3 +88,36 @@ func (rtmsg *Articles) Send(w http.ResponseWriter) error {
 	fmt.Fprint(w, strResponseMsg)
 	return err
 }
+
+// ShortenURLs 使用shortener缩短每篇文章的URL，应在Send之前调用
+//
+// Content会替换为副本，不修改调用方传入的切片；缩短失败的文章保留原链接，返回遇到的第一个错误
+func (rtmsg *Articles) ShortenURLs(ctx context.Context, shortener URLShortener) error {
+	content := make([]Article, len(rtmsg.Content))
+	copy(content, rtmsg.Content)
+	rtmsg.Content = content
+
+	var firstErr error
+	for index := 0; index < len(content); index++ {
+		if err := ctx.Err(); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			break
+		}
+		if content[index].URL == "" {
+			continue
+		}
+		shortURL, err := shortener(ctx, content[index].URL)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if shortURL != "" {
+			content[index].URL = shortURL
+		}
+	}
+	return firstErr
+}
@@ -1,5 +1,37 @@
 package message
 
+import "strings"
+
+// 事件类型枚举
+const (
+	EventSubscribe   = "subscribe"
+	EventUnsubscribe = "unsubscribe"
+	EventScan        = "SCAN"
+	EventLocation    = "LOCATION"
+	EventClick       = "CLICK"
+	EventView        = "VIEW"
+
+	EventTemplateSendJobFinish = "TEMPLATESENDJOBFINISH"
+
+	// 自定义菜单扫码、发图、发送位置事件
+	EventScancodePush    = "scancode_push"
+	EventScancodeWaitMsg = "scancode_waitmsg"
+	EventPicSysPhoto     = "pic_sysphoto"
+	EventPicPhotoOrAlbum = "pic_photo_or_album"
+	EventPicWeixin       = "pic_weixin"
+	EventLocationSelect  = "location_select"
+)
+
+// 模板消息发送结果
+const (
+	TemplateSendSuccess      = "success"
+	TemplateSendUserBlock    = "failed:user block"
+	TemplateSendSystemFailed = "failed: system failed"
+)
+
+// qrScenePrefix 扫描带参数二维码关注时EventKey的前缀
+const qrScenePrefix = "qrscene_"
+
 // Event 事件消息体
 type Event struct {
 	ToUserName   string
@@ -11,4 +43,154 @@ type Event struct {
 	Latitude     float64
 	Longitude    float64
 	Precision    float64
+
+	// 自定义菜单事件附加信息，其他事件为nil
+	ScanCodeInfo     *ScanCodeInfo
+	SendPicsInfo     *SendPicsInfo
+	SendLocationInfo *SendLocationInfo
+}
+
+// SubscribeEvent 关注事件，通过带参数二维码关注时SceneValue为场景值
+type SubscribeEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	EventKey     string
+	Ticket       string
+	SceneValue   string
+}
+
+// UnsubscribeEvent 取消关注事件
+type UnsubscribeEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+}
+
+// ScanEvent 已关注用户扫描带参数二维码事件
+type ScanEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	EventKey     string
+	Ticket       string
+	SceneValue   string
+}
+
+// SceneEvent 扫描带参数二维码的关注或扫码事件，Event为subscribe或SCAN
+type SceneEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	Event        string
+	SceneValue   string
+	Ticket       string
+}
+
+// LocationEvent 上报地理位置事件
+type LocationEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	Latitude     float64
+	Longitude    float64
+	Precision    float64
+}
+
+// ClickEvent 点击菜单拉取消息事件
+type ClickEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	EventKey     string
+}
+
+// ViewEvent 点击菜单跳转链接事件，EventKey为跳转的URL
+type ViewEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	EventKey     string
+	MenuID       string
+}
+
+// ScanCodeInfo 扫码事件的扫描信息
+type ScanCodeInfo struct {
+	ScanType   string `xml:"ScanType"`
+	ScanResult string `xml:"ScanResult"`
+}
+
+// SendPicsInfo 发图事件的图片信息
+type SendPicsInfo struct {
+	Count   int       `xml:"Count"`
+	PicList []PicItem `xml:"PicList>item"`
+}
+
+// PicItem 发图事件的单张图片
+type PicItem struct {
+	PicMd5Sum string `xml:"PicMd5Sum"`
+}
+
+// SendLocationInfo 发送位置事件的位置信息
+type SendLocationInfo struct {
+	LocationX float64 `xml:"Location_X"`
+	LocationY float64 `xml:"Location_Y"`
+	Scale     float64 `xml:"Scale"`
+	Label     string  `xml:"Label"`
+	Poiname   string  `xml:"Poiname"`
+}
+
+// MenuEvent 自定义菜单事件，按事件类型只有对应的附加信息不为nil
+type MenuEvent struct {
+	ToUserName       string
+	FromUserName     string
+	CreateTime       int64
+	Event            string
+	EventKey         string
+	ScanCodeInfo     *ScanCodeInfo
+	SendPicsInfo     *SendPicsInfo
+	SendLocationInfo *SendLocationInfo
+}
+
+// ScanCodeEvent 扫码推事件(scancode_push)与扫码推事件且弹出"消息接收中"提示框的事件(scancode_waitmsg)
+type ScanCodeEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	Event        string
+	EventKey     string
+	ScanCodeInfo ScanCodeInfo
+}
+
+// SendPicsEvent 弹出系统拍照、拍照或者相册、微信相册发图器的事件
+type SendPicsEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	Event        string
+	EventKey     string
+	SendPicsInfo SendPicsInfo
+}
+
+// LocationSelectEvent 弹出地理位置选择器的事件
+type LocationSelectEvent struct {
+	ToUserName       string
+	FromUserName     string
+	CreateTime       int64
+	EventKey         string
+	SendLocationInfo SendLocationInfo
+}
+
+// TemplateSendJobFinishEvent 模板消息发送结果事件
+type TemplateSendJobFinishEvent struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	MsgID        int64
+	Status       string
+}
+
+// SceneValue 从关注或扫码事件的EventKey中解析二维码场景值
+func SceneValue(eventKey string) string {
+	return strings.TrimPrefix(eventKey, qrScenePrefix)
 }
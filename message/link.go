@@ -0,0 +1,12 @@
+package message
+
+// Link 链接消息体
+type Link struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	Title        string
+	Description  string
+	URL          string
+	MsgID        int64
+}
@@ -0,0 +1,13 @@
+package message
+
+// Location 地理位置消息体
+type Location struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	LocationX    float64
+	LocationY    float64
+	Scale        float64
+	Label        string
+	MsgID        int64
+}
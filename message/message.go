@@ -47,6 +47,14 @@ type requestMessage struct {
 	Latitude     float64 `xml:"Latitude"`
 	Longitude    float64 `xml:"Longitude"`
 	Precision    float64 `xml:"Precision"`
+	MenuID       string  `xml:"MenuId"`
+	// 模板消息发送结果事件中消息ID的标签为MsgID
+	EventMsgID int64  `xml:"MsgID"`
+	Status     string `xml:"Status"`
+	// 自定义菜单事件附加信息
+	ScanCodeInfo     *ScanCodeInfo     `xml:"ScanCodeInfo"`
+	SendPicsInfo     *SendPicsInfo     `xml:"SendPicsInfo"`
+	SendLocationInfo *SendLocationInfo `xml:"SendLocationInfo"`
 }
 
 // PublicMessage 公共微信消息头数据
@@ -84,6 +92,12 @@ type RawMessage struct {
 	Latitude     float64
 	Longitude    float64
 	Precision    float64
+	MenuID       string
+	Status       string
+
+	ScanCodeInfo     *ScanCodeInfo
+	SendPicsInfo     *SendPicsInfo
+	SendLocationInfo *SendLocationInfo
 }
 
 // ParseMsg 解析服务器发来的消息
@@ -97,6 +111,9 @@ func ParseMsg(contentBytes []byte) (RawMessage, error) {
 	requestMsg.FromUserName = msg.FromUserName
 	requestMsg.ToUserName = msg.ToUserName
 	requestMsg.MsgID = msg.MsgID
+	if requestMsg.MsgID == 0 {
+		requestMsg.MsgID = msg.EventMsgID
+	}
 	requestMsg.CreateTime = msg.CreateTime
 	requestMsg.MediaID = msg.MediaID
 	requestMsg.PicURL = msg.PicURL
@@ -104,6 +121,7 @@ func ParseMsg(contentBytes []byte) (RawMessage, error) {
 	requestMsg.Recognition = msg.Recognition
 	requestMsg.ThumbMediaID = msg.ThumbMediaID
 	requestMsg.Title = msg.Title
+	requestMsg.Description = msg.Description
 	requestMsg.URL = msg.URL
 	requestMsg.LocationX = msg.LocationX
 	requestMsg.LocationY = msg.LocationY
@@ -115,5 +133,10 @@ func ParseMsg(contentBytes []byte) (RawMessage, error) {
 	requestMsg.Latitude = msg.Latitude
 	requestMsg.Longitude = msg.Longitude
 	requestMsg.Precision = msg.Precision
+	requestMsg.MenuID = msg.MenuID
+	requestMsg.Status = msg.Status
+	requestMsg.ScanCodeInfo = msg.ScanCodeInfo
+	requestMsg.SendPicsInfo = msg.SendPicsInfo
+	requestMsg.SendLocationInfo = msg.SendLocationInfo
 	return requestMsg, err
 }
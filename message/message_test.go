@@ -0,0 +1,32 @@
+package message
+
+import "testing"
+
+const shortVideoXML = `<xml>
+<ToUserName><![CDATA[toUser]]></ToUserName>
+<FromUserName><![CDATA[fromUser]]></FromUserName>
+<CreateTime>1357290913</CreateTime>
+<MsgType><![CDATA[shortvideo]]></MsgType>
+<MediaId><![CDATA[media_id]]></MediaId>
+<ThumbMediaId><![CDATA[thumb_media_id]]></ThumbMediaId>
+<MsgId>1234567890123456</MsgId>
+</xml>`
+
+func TestParseShortVideo(t *testing.T) {
+	msg, err := ParseMsg([]byte(shortVideoXML))
+	if err != nil {
+		t.Fatalf("ParseMsg: %v", err)
+	}
+	want := RawMessage{
+		ToUserName:   "toUser",
+		FromUserName: "fromUser",
+		CreateTime:   1357290913,
+		MsgType:      ShortvideoMsg,
+		MediaID:      "media_id",
+		ThumbMediaID: "thumb_media_id",
+		MsgID:        1234567890123456,
+	}
+	if msg != want {
+		t.Errorf("ParseMsg = %+v, want %+v", msg, want)
+	}
+}
@@ -23,18 +23,22 @@ type Music struct {
 	ToUserName   string
 	FromUserName string
 	CreateTime   int64
-	MediaID      string
 	Title        string
 	Description  string
+	MusicURL     string
+	HQMusicURL   string
+	ThumbMediaID string
 }
 
 func (rtmsg *Music) formatLogicMsg() (string, error) {
-	destMsg := responseVideoMessage{}
-	destMsg.MediaID = rtmsg.MediaID
+	destMsg := responseMusicMessage{}
 	destMsg.ToUserName = rtmsg.ToUserName
 	destMsg.FromUserName = rtmsg.FromUserName
 	destMsg.Title = rtmsg.Title
 	destMsg.Description = rtmsg.Description
+	destMsg.MusicURL = rtmsg.MusicURL
+	destMsg.HQMusicURL = rtmsg.HQMusicURL
+	destMsg.ThumbMediaID = rtmsg.ThumbMediaID
 	if rtmsg.CreateTime == 0 {
 		destMsg.CreateTime = util.GetCurrTimeStamp()
 	} else {
@@ -44,14 +48,14 @@ func (rtmsg *Music) formatLogicMsg() (string, error) {
 
 	responseRawXMLMsg, err := xml.Marshal(destMsg)
 	if err != nil {
-		log.Println("Build Response Text Message Error")
+		log.Println("Build Response Music Message Error")
 		return "", err
 	}
 	result := string(responseRawXMLMsg)
 	return result, err
 }
 
-// Send 向服务器发送文字消息
+// Send 向服务器发送音乐消息
 func (rtmsg *Music) Send(w http.ResponseWriter) error {
 	header := w.Header()
 	if val := header["Content-Type"]; len(val) == 0 {
@@ -0,0 +1,75 @@
+package message
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const (
+	testToUser     = "toUser"
+	testFromUser   = "fromUser"
+	testCreateTime = 1357290913
+)
+
+const testHeader = `<ToUserName>toUser</ToUserName><FromUserName>fromUser</FromUserName><CreateTime>1357290913</CreateTime>`
+
+func TestReplyGolden(t *testing.T) {
+	cases := []struct {
+		name  string
+		reply interface {
+			Send(w http.ResponseWriter) error
+		}
+		want string
+	}{
+		{
+			"text",
+			&Text{ToUserName: testToUser, FromUserName: testFromUser, CreateTime: testCreateTime, Content: "你好 <&>"},
+			`<xml>` + testHeader + `<MsgType>text</MsgType><Content>你好 &lt;&amp;&gt;</Content></xml>`,
+		},
+		{
+			"image",
+			&Image{ToUserName: testToUser, FromUserName: testFromUser, CreateTime: testCreateTime, MediaID: "media_id"},
+			`<xml>` + testHeader + `<MsgType>image</MsgType><Image><MediaId>media_id</MediaId></Image></xml>`,
+		},
+		{
+			"voice",
+			&Voice{ToUserName: testToUser, FromUserName: testFromUser, CreateTime: testCreateTime, MediaID: "media_id"},
+			`<xml>` + testHeader + `<MsgType>voice</MsgType><Voice><MediaId>media_id</MediaId></Voice></xml>`,
+		},
+		{
+			"video",
+			&Video{ToUserName: testToUser, FromUserName: testFromUser, CreateTime: testCreateTime, MediaID: "media_id", Title: "title", Description: "description"},
+			`<xml>` + testHeader + `<MsgType>video</MsgType><Video><MediaId>media_id</MediaId><Title>title</Title><Description>description</Description></Video></xml>`,
+		},
+		{
+			"music",
+			&Music{ToUserName: testToUser, FromUserName: testFromUser, CreateTime: testCreateTime, Title: "title", Description: "description", MusicURL: "http://example.com/a.mp3", HQMusicURL: "http://example.com/a_hq.mp3", ThumbMediaID: "thumb_media_id"},
+			`<xml>` + testHeader + `<MsgType>music</MsgType><Music><Title>title</Title><Description>description</Description><MusicUrl>http://example.com/a.mp3</MusicUrl><HQMusicUrl>http://example.com/a_hq.mp3</HQMusicUrl><ThumbMediaId>thumb_media_id</ThumbMediaId></Music></xml>`,
+		},
+		{
+			"articles",
+			&Articles{ToUserName: testToUser, FromUserName: testFromUser, CreateTime: testCreateTime, Content: []Article{
+				{Title: "title1", Description: "description1", PicURL: "http://example.com/1.jpg", URL: "http://example.com/1?a=1&b=2"},
+				{Title: "title2", URL: "http://example.com/2"},
+			}},
+			`<xml>` + testHeader + `<MsgType>news</MsgType><ArticleCount>2</ArticleCount><Articles>` +
+				`<item><Title>title1</Title><Description>description1</Description><PicUrl>http://example.com/1.jpg</PicUrl><Url>http://example.com/1?a=1&amp;b=2</Url></item>` +
+				`<item><Title>title2</Title><Description></Description><PicUrl></PicUrl><Url>http://example.com/2</Url></item>` +
+				`</Articles></xml>`,
+		},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		if err := tc.reply.Send(w); err != nil {
+			t.Errorf("%s: Send: %v", tc.name, err)
+			continue
+		}
+		if got := w.Body.String(); got != tc.want {
+			t.Errorf("%s: Send wrote\n%s\nwant\n%s", tc.name, got, tc.want)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != xmlContentType[0] {
+			t.Errorf("%s: Content-Type = %q, want %q", tc.name, contentType, xmlContentType[0])
+		}
+	}
+}
@@ -22,6 +22,7 @@ type Video struct {
 	FromUserName string
 	CreateTime   int64
 	MediaID      string
+	ThumbMediaID string
 	Title        string
 	Description  string
 	MsgID        int64
@@ -29,6 +30,12 @@ type Video struct {
 
 // ShortVideo 小视频消息体
 type ShortVideo struct {
+	ToUserName   string
+	FromUserName string
+	CreateTime   int64
+	MediaID      string
+	ThumbMediaID string
+	MsgID        int64
 }
 
 func (rtmsg *Video) formatLogicMsg() (string, error) {
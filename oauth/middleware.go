@@ -0,0 +1,192 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const stateCookieName = "wxoauth_state"
+
+// stateMaxAge state Cookie有效期，单位秒
+const stateMaxAge = 300
+
+// 中间件错误
+var (
+	ErrStateMismatch   = errors.New("oauth: state mismatch")
+	ErrAuthorizeDenied = errors.New("oauth: user denied authorization")
+)
+
+type contextKey struct{}
+
+// TokenHandle 授权成功后的回调，可在此用token获取用户信息，返回错误时授权失败
+type TokenHandle func(r *http.Request, token *Token) error
+
+// Middleware 网页授权中间件，没有会话时跳转到授权页，授权回调后保存会话并跳回原地址，再将会话放入请求的Context
+type Middleware struct {
+	oauth       *OAuth
+	scope       string
+	store       SessionStore
+	next        http.Handler
+	tokenHandle TokenHandle
+	errorHandle server.ErrorHandle
+	baseURL     string
+}
+
+// Middleware 创建网页授权中间件，store用于保存授权后的会话，next中可通过OpenIDFromContext获取openid
+func (oauth *OAuth) Middleware(scope string, store SessionStore, next http.Handler) *Middleware {
+	m := new(Middleware)
+	m.oauth = oauth
+	m.scope = scope
+	m.store = store
+	m.next = next
+	return m
+}
+
+// SetTokenHandle 设置授权成功后的回调，网页授权AccessToken只在此回调中可用
+func (m *Middleware) SetTokenHandle(handle TokenHandle) {
+	m.tokenHandle = handle
+}
+
+// SetErrorHandle 设置授权失败时的回调，可用于日志与统计
+func (m *Middleware) SetErrorHandle(handle server.ErrorHandle) {
+	m.errorHandle = handle
+}
+
+// SetRedirectBaseURL 设置授权回调地址的协议与域名，如https://h5.example.com，可带路径前缀，回调地址为baseURL加请求路径
+//
+// 未设置时由请求的Host与X-Forwarded-Proto推断，部署在代理之后或Host不可信时应当设置
+func (m *Middleware) SetRedirectBaseURL(baseURL string) {
+	m.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+func (m *Middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	session, err := m.store.Load(r)
+	if err != nil {
+		m.handleError(err, r)
+	}
+	if session != nil && m.scopeSatisfied(session) {
+		ctx := context.WithValue(r.Context(), contextKey{}, session)
+		m.next.ServeHTTP(w, r.WithContext(ctx))
+		return
+	}
+
+	query := r.URL.Query()
+	code := query.Get("code")
+	state := query.Get("state")
+	if code == "" {
+		// 用户拒绝授权时回调只带state，不再跳转避免循环
+		if state != "" {
+			m.handleError(ErrAuthorizeDenied, r)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		m.redirect(w, r)
+		return
+	}
+
+	cookie, err := r.Cookie(stateCookieName)
+	if err != nil || cookie.Value == "" || cookie.Value != state {
+		m.handleError(ErrStateMismatch, r)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	token, err := m.oauth.ExchangeCodeContext(r.Context(), code)
+	if err == nil && m.tokenHandle != nil {
+		err = m.tokenHandle(r, token)
+	}
+	if err != nil {
+		m.handleError(err, r)
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	session = &Session{OpenID: token.OpenID, UnionID: token.UnionID, Scope: token.Scope}
+	if err := m.store.Save(w, r, session); err != nil {
+		m.handleError(err, r)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: stateCookieName, Path: "/", MaxAge: -1, HttpOnly: true})
+	// 跳回去掉code与state的原地址，刷新页面时不会重复使用code
+	http.Redirect(w, r, m.callbackURL(r), http.StatusFound)
+}
+
+// scopeSatisfied 会话的授权作用域是否满足中间件要求，snsapi_userinfo包含snsapi_base
+func (m *Middleware) scopeSatisfied(session *Session) bool {
+	return m.scope != ScopeUserInfo || strings.Contains(session.Scope, ScopeUserInfo)
+}
+
+func (m *Middleware) redirect(w http.ResponseWriter, r *http.Request) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		m.handleError(err, r)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	state := hex.EncodeToString(buf)
+	http.SetCookie(w, &http.Cookie{Name: stateCookieName, Value: state, Path: "/", MaxAge: stateMaxAge, Secure: m.secure(r), HttpOnly: true, SameSite: http.SameSiteLaxMode})
+	http.Redirect(w, r, m.oauth.AuthorizeURL(m.callbackURL(r), m.scope, state), http.StatusFound)
+}
+
+func (m *Middleware) handleError(err error, r *http.Request) {
+	if m.errorHandle != nil {
+		m.errorHandle(err, r)
+		return
+	}
+	log.Println("OAuth Error", err)
+}
+
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https"
+}
+
+// secure state Cookie是否只通过HTTPS发送
+func (m *Middleware) secure(r *http.Request) bool {
+	if m.baseURL != "" {
+		return strings.HasPrefix(m.baseURL, "https://")
+	}
+	return isHTTPS(r)
+}
+
+// callbackURL 以当前请求地址作为授权回调地址，去掉上次授权遗留的code与state
+func (m *Middleware) callbackURL(r *http.Request) string {
+	query := r.URL.Query()
+	query.Del("code")
+	query.Del("state")
+	if m.baseURL != "" {
+		callback := m.baseURL + r.URL.EscapedPath()
+		if len(query) > 0 {
+			callback += "?" + query.Encode()
+		}
+		return callback
+	}
+
+	scheme := "http"
+	if isHTTPS(r) {
+		scheme = "https"
+	}
+	callback := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: query.Encode()}
+	return callback.String()
+}
+
+// SessionFromContext 获取中间件放入Context的会话，不存在时返回nil
+func SessionFromContext(ctx context.Context) *Session {
+	session, _ := ctx.Value(contextKey{}).(*Session)
+	return session
+}
+
+// OpenIDFromContext 获取中间件放入Context的openid，不存在时返回空字符串
+func OpenIDFromContext(ctx context.Context) string {
+	if session := SessionFromContext(ctx); session != nil {
+		return session.OpenID
+	}
+	return ""
+}
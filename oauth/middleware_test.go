@@ -0,0 +1,151 @@
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+var testSessionKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newTestMiddleware(t *testing.T) (*Middleware, *int) {
+	exchanges := new(int)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*exchanges++
+		if r.URL.Path != accessTokenPath || r.URL.Query().Get("code") != "CODE" {
+			fmt.Fprint(w, `{"errcode":40029,"errmsg":"invalid code"}`)
+			return
+		}
+		fmt.Fprint(w, `{"access_token":"ACCESS_TOKEN","expires_in":7200,"refresh_token":"REFRESH_TOKEN","openid":"OPENID","scope":"snsapi_base"}`)
+	}))
+	t.Cleanup(api.Close)
+
+	client := server.NewAPIClient(nil)
+	client.SetBaseURL(api.URL)
+	oauth := New("APPID", "SECRET")
+	oauth.SetClient(client)
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, OpenIDFromContext(r.Context()))
+	})
+	store, err := NewCookieSessionStore(testSessionKey, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := oauth.Middleware(ScopeBase, store, next)
+	m.SetErrorHandle(func(err error, r *http.Request) {})
+	return m, exchanges
+}
+
+func serve(m *Middleware, target string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddlewareFlow(t *testing.T) {
+	m, exchanges := newTestMiddleware(t)
+
+	// 没有会话时跳转到授权页
+	w := serve(m, "http://h5.example.com/page?x=1", nil)
+	if w.Code != http.StatusFound {
+		t.Fatalf("first request status = %d, want %d", w.Code, http.StatusFound)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(location.String(), AuthorizeURL) {
+		t.Fatalf("redirect to %q", w.Header().Get("Location"))
+	}
+	if redirectURI := location.Query().Get("redirect_uri"); redirectURI != "http://h5.example.com/page?x=1" {
+		t.Errorf("redirect_uri = %q", redirectURI)
+	}
+	state := location.Query().Get("state")
+	stateCookies := w.Result().Cookies()
+
+	// 授权回调换取openid后保存会话并跳回原地址
+	callback := "http://h5.example.com/page?x=1&code=CODE&state=" + state
+	w = serve(m, callback, stateCookies)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "http://h5.example.com/page?x=1" {
+		t.Fatalf("callback = %d %q, want redirect to original page", w.Code, w.Header().Get("Location"))
+	}
+	var session *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == sessionCookieName {
+			session = cookie
+		}
+	}
+	if session == nil {
+		t.Fatal("session cookie not set")
+	}
+
+	// 之后的请求和重新打开回调地址都直接使用会话
+	for _, target := range []string{"http://h5.example.com/page?x=1", "http://h5.example.com/other", callback} {
+		w = serve(m, target, []*http.Cookie{session})
+		if w.Code != http.StatusOK || w.Body.String() != "OPENID" {
+			t.Errorf("%s: %d %q, want 200 \"OPENID\"", target, w.Code, w.Body.String())
+		}
+	}
+	if *exchanges != 1 {
+		t.Errorf("code exchanged %d times, want 1", *exchanges)
+	}
+}
+
+func TestMiddlewareRejects(t *testing.T) {
+	m, _ := newTestMiddleware(t)
+
+	w := serve(m, "http://h5.example.com/page?code=CODE&state=abc", []*http.Cookie{{Name: stateCookieName, Value: "other"}})
+	if w.Code != http.StatusForbidden {
+		t.Errorf("state mismatch status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = serve(m, "http://h5.example.com/page?state=abc", nil)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("denied status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	// 篡改或用其他密钥签名的会话重新发起授权
+	otherStore, err := NewCookieSessionStore([]byte("another-key-another-key-another-k"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := httptest.NewRecorder()
+	otherStore.Save(forged, httptest.NewRequest(http.MethodGet, "/", nil), &Session{OpenID: "FORGED"})
+	w = serve(m, "http://h5.example.com/page", forged.Result().Cookies())
+	if w.Code != http.StatusFound {
+		t.Errorf("forged session status = %d, want %d", w.Code, http.StatusFound)
+	}
+}
+
+func TestMiddlewareRedirectBaseURL(t *testing.T) {
+	m, _ := newTestMiddleware(t)
+	m.SetRedirectBaseURL("https://h5.example.com/app/")
+
+	// 配置了回调域名时忽略请求中的Host与X-Forwarded-Proto
+	r := httptest.NewRequest(http.MethodGet, "http://evil.example.com/page?x=1", nil)
+	r.Header.Set("X-Forwarded-Proto", "http")
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, r)
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redirectURI := location.Query().Get("redirect_uri"); redirectURI != "https://h5.example.com/app/page?x=1" {
+		t.Errorf("redirect_uri = %q", redirectURI)
+	}
+	stateCookies := w.Result().Cookies()
+	if len(stateCookies) != 1 || !stateCookies[0].Secure {
+		t.Errorf("state cookie = %+v, want secure", stateCookies)
+	}
+
+	w = serve(m, "http://evil.example.com/page?x=1&code=CODE&state="+location.Query().Get("state"), stateCookies)
+	if w.Code != http.StatusFound || w.Header().Get("Location") != "https://h5.example.com/app/page?x=1" {
+		t.Errorf("callback = %d %q, want redirect to configured base", w.Code, w.Header().Get("Location"))
+	}
+}
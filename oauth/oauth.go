@@ -0,0 +1,161 @@
+package oauth
+
+import (
+	"context"
+	"net/url"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+// AuthorizeURL 网页授权地址
+var AuthorizeURL = "https://open.weixin.qq.com/connect/oauth2/authorize"
+
+const (
+	accessTokenPath  = "/sns/oauth2/access_token"
+	refreshTokenPath = "/sns/oauth2/refresh_token"
+	authPath         = "/sns/auth"
+	userInfoPath     = "/sns/userinfo"
+)
+
+// 网页授权作用域
+const (
+	// ScopeBase 静默授权，只能获取openid
+	ScopeBase = "snsapi_base"
+	// ScopeUserInfo 需用户确认，可获取用户基本信息
+	ScopeUserInfo = "snsapi_userinfo"
+)
+
+// 用户信息语言
+const (
+	LangZhCN = "zh_CN"
+	LangZhTW = "zh_TW"
+	LangEn   = "en"
+)
+
+// OAuth 网页授权客户端
+type OAuth struct {
+	appid     string
+	appsecret string
+	client    *server.APIClient
+}
+
+// Token 网页授权AccessToken，与基础接口的AccessToken不同
+type Token struct {
+	AccessToken    string `json:"access_token"`
+	ExpiresIn      int    `json:"expires_in"`
+	RefreshToken   string `json:"refresh_token"`
+	OpenID         string `json:"openid"`
+	Scope          string `json:"scope"`
+	IsSnapshotUser int    `json:"is_snapshotuser,omitempty"`
+	UnionID        string `json:"unionid,omitempty"`
+}
+
+// UserInfo 网页授权获取的用户信息
+type UserInfo struct {
+	OpenID     string   `json:"openid"`
+	Nickname   string   `json:"nickname"`
+	Sex        int      `json:"sex"`
+	Province   string   `json:"province"`
+	City       string   `json:"city"`
+	Country    string   `json:"country"`
+	HeadImgURL string   `json:"headimgurl"`
+	Privilege  []string `json:"privilege"`
+	UnionID    string   `json:"unionid,omitempty"`
+}
+
+// New 创建网页授权客户端
+func New(appid string, appsecret string) *OAuth {
+	oauth := new(OAuth)
+	oauth.appid = appid
+	oauth.appsecret = appsecret
+	oauth.client = server.NewAPIClient(nil)
+	return oauth
+}
+
+// SetClient 设置调用接口的客户端，客户端不能带有AccessToken管理
+func (oauth *OAuth) SetClient(client *server.APIClient) {
+	if client == nil {
+		client = server.NewAPIClient(nil)
+	}
+	oauth.client = client
+}
+
+// AuthorizeURL 生成网页授权地址，redirectURI为授权后的回调地址
+func (oauth *OAuth) AuthorizeURL(redirectURI string, scope string, state string) string {
+	// 微信要求参数按此顺序排列，不能使用url.Values.Encode排序后的结果
+	return AuthorizeURL + "?appid=" + url.QueryEscape(oauth.appid) +
+		"&redirect_uri=" + url.QueryEscape(redirectURI) +
+		"&response_type=code&scope=" + url.QueryEscape(scope) +
+		"&state=" + url.QueryEscape(state) + "#wechat_redirect"
+}
+
+// ExchangeCode 通过回调中的code换取网页授权AccessToken与openid
+func (oauth *OAuth) ExchangeCode(code string) (*Token, error) {
+	return oauth.ExchangeCodeContext(context.Background(), code)
+}
+
+// ExchangeCodeContext 通过回调中的code换取网页授权AccessToken与openid
+func (oauth *OAuth) ExchangeCodeContext(ctx context.Context, code string) (*Token, error) {
+	params := url.Values{}
+	params.Set("appid", oauth.appid)
+	params.Set("secret", oauth.appsecret)
+	params.Set("code", code)
+	params.Set("grant_type", "authorization_code")
+	token := new(Token)
+	if err := oauth.client.GetContext(ctx, accessTokenPath, params, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RefreshToken 使用refresh_token刷新网页授权AccessToken
+func (oauth *OAuth) RefreshToken(refreshToken string) (*Token, error) {
+	return oauth.RefreshTokenContext(context.Background(), refreshToken)
+}
+
+// RefreshTokenContext 使用refresh_token刷新网页授权AccessToken
+func (oauth *OAuth) RefreshTokenContext(ctx context.Context, refreshToken string) (*Token, error) {
+	params := url.Values{}
+	params.Set("appid", oauth.appid)
+	params.Set("grant_type", "refresh_token")
+	params.Set("refresh_token", refreshToken)
+	token := new(Token)
+	if err := oauth.client.GetContext(ctx, refreshTokenPath, params, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ValidateToken 检验网页授权AccessToken是否有效，无效时返回*server.APIError
+func (oauth *OAuth) ValidateToken(accessToken string, openID string) error {
+	return oauth.ValidateTokenContext(context.Background(), accessToken, openID)
+}
+
+// ValidateTokenContext 检验网页授权AccessToken是否有效，无效时返回*server.APIError
+func (oauth *OAuth) ValidateTokenContext(ctx context.Context, accessToken string, openID string) error {
+	params := url.Values{}
+	params.Set("access_token", accessToken)
+	params.Set("openid", openID)
+	return oauth.client.GetContext(ctx, authPath, params, nil)
+}
+
+// GetUserInfo 获取用户信息，需要snsapi_userinfo作用域的AccessToken，lang为空时使用简体中文
+func (oauth *OAuth) GetUserInfo(accessToken string, openID string, lang string) (*UserInfo, error) {
+	return oauth.GetUserInfoContext(context.Background(), accessToken, openID, lang)
+}
+
+// GetUserInfoContext 获取用户信息，需要snsapi_userinfo作用域的AccessToken，lang为空时使用简体中文
+func (oauth *OAuth) GetUserInfoContext(ctx context.Context, accessToken string, openID string, lang string) (*UserInfo, error) {
+	if lang == "" {
+		lang = LangZhCN
+	}
+	params := url.Values{}
+	params.Set("access_token", accessToken)
+	params.Set("openid", openID)
+	params.Set("lang", lang)
+	info := new(UserInfo)
+	if err := oauth.client.GetContext(ctx, userInfoPath, params, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
@@ -0,0 +1,129 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultSessionMaxAge 默认会话有效期
+const DefaultSessionMaxAge = 7 * 24 * time.Hour
+
+const sessionCookieName = "wxoauth_session"
+
+// MinSessionKeyBytes 会话签名密钥的最小长度
+const MinSessionKeyBytes = 32
+
+// 会话错误
+var (
+	// ErrInvalidSession 会话Cookie格式错误或签名不匹配
+	ErrInvalidSession = errors.New("oauth: invalid session")
+	// ErrShortSessionKey 会话签名密钥过短，任何人都能伪造会话
+	ErrShortSessionKey = errors.New("oauth: session key must be at least 32 bytes")
+)
+
+// Session 网页授权成功后保存的用户会话
+type Session struct {
+	OpenID    string    `json:"openid"`
+	UnionID   string    `json:"unionid,omitempty"`
+	Scope     string    `json:"scope"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionStore 网页授权会话存储接口，可替换为服务端存储
+type SessionStore interface {
+	// Load 读取请求对应的会话，没有会话或会话已过期时返回nil
+	Load(r *http.Request) (*Session, error)
+	// Save 保存会话，session的ExpiresAt为空时由存储决定有效期
+	Save(w http.ResponseWriter, r *http.Request, session *Session) error
+}
+
+// CookieSessionStore 以HMAC-SHA256签名的Cookie保存会话，Cookie中只有openid等标识，不含AccessToken
+type CookieSessionStore struct {
+	key    []byte
+	maxAge time.Duration
+}
+
+// NewCookieSessionStore 创建Cookie会话存储，key为至少32字节的随机签名密钥，多实例部署时需使用相同的key，maxAge为0时使用DefaultSessionMaxAge
+func NewCookieSessionStore(key []byte, maxAge time.Duration) (*CookieSessionStore, error) {
+	if len(key) < MinSessionKeyBytes {
+		return nil, ErrShortSessionKey
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultSessionMaxAge
+	}
+	cs := new(CookieSessionStore)
+	cs.key = append([]byte(nil), key...)
+	cs.maxAge = maxAge
+	return cs, nil
+}
+
+// Load 读取并校验会话Cookie
+func (cs *CookieSessionStore) Load(r *http.Request) (*Session, error) {
+	if len(cs.key) < MinSessionKeyBytes {
+		return nil, ErrShortSessionKey
+	}
+	cookie, err := r.Cookie(sessionCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, nil
+	}
+	parts := strings.SplitN(cookie.Value, ".", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidSession
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, cs.sign(parts[0])) {
+		return nil, ErrInvalidSession
+	}
+	content, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidSession
+	}
+	session := new(Session)
+	if err := json.Unmarshal(content, session); err != nil {
+		return nil, ErrInvalidSession
+	}
+	if session.OpenID == "" || time.Now().After(session.ExpiresAt) {
+		return nil, nil
+	}
+	return session, nil
+}
+
+// Save 签名后写入会话Cookie
+func (cs *CookieSessionStore) Save(w http.ResponseWriter, r *http.Request, session *Session) error {
+	if len(cs.key) < MinSessionKeyBytes {
+		return ErrShortSessionKey
+	}
+	saved := *session
+	if saved.ExpiresAt.IsZero() {
+		saved.ExpiresAt = time.Now().Add(cs.maxAge)
+	}
+	content, err := json.Marshal(&saved)
+	if err != nil {
+		return err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(content)
+	value := payload + "." + base64.RawURLEncoding.EncodeToString(cs.sign(payload))
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookieName,
+		Value:    value,
+		Path:     "/",
+		Expires:  saved.ExpiresAt,
+		MaxAge:   int(time.Until(saved.ExpiresAt) / time.Second),
+		Secure:   isHTTPS(r),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (cs *CookieSessionStore) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, cs.key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
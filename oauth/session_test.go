@@ -0,0 +1,58 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCookieSessionStoreRejectsShortKey(t *testing.T) {
+	for _, key := range [][]byte{nil, {}, []byte("short"), make([]byte, MinSessionKeyBytes-1)} {
+		if store, err := NewCookieSessionStore(key, 0); store != nil || err != ErrShortSessionKey {
+			t.Errorf("NewCookieSessionStore(%d bytes) = %v, %v, want nil, %v", len(key), store, err, ErrShortSessionKey)
+		}
+	}
+
+	// 零值存储没有密钥，不能保存也不能读取会话
+	store := new(CookieSessionStore)
+	w := httptest.NewRecorder()
+	if err := store.Save(w, httptest.NewRequest(http.MethodGet, "/", nil), &Session{OpenID: "OPENID"}); err != ErrShortSessionKey {
+		t.Errorf("zero store Save err = %v, want %v", err, ErrShortSessionKey)
+	}
+	if len(w.Result().Cookies()) != 0 {
+		t.Error("zero store wrote a cookie")
+	}
+
+	// 以空密钥签名伪造的会话不能被读取
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"openid":"FORGED","expires_at":"2100-01-01T00:00:00Z"}`))
+	mac := hmac.New(sha256.New, nil)
+	mac.Write([]byte(payload))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: sessionCookieName, Value: payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))})
+	if session, err := store.Load(r); session != nil || err != ErrShortSessionKey {
+		t.Errorf("zero store Load = %+v, %v, want nil, %v", session, err, ErrShortSessionKey)
+	}
+}
+
+func TestCookieSessionStoreRoundTrip(t *testing.T) {
+	store, err := NewCookieSessionStore(testSessionKey, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	if err := store.Save(w, httptest.NewRequest(http.MethodGet, "/", nil), &Session{OpenID: "OPENID", Scope: ScopeBase}); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range w.Result().Cookies() {
+		r.AddCookie(cookie)
+	}
+	session, err := store.Load(r)
+	if err != nil || session == nil || session.OpenID != "OPENID" {
+		t.Errorf("Load = %+v, %v, want OPENID", session, err)
+	}
+}
@@ -0,0 +1,134 @@
+package qrcode
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const createPath = "/cgi-bin/qrcode/create"
+
+// ShowQRCodeURL 通过ticket换取二维码图片的地址
+var ShowQRCodeURL = "https://mp.weixin.qq.com/cgi-bin/showqrcode"
+
+// 二维码类型
+const (
+	actionScene         = "QR_SCENE"
+	actionStrScene      = "QR_STR_SCENE"
+	actionLimitScene    = "QR_LIMIT_SCENE"
+	actionLimitStrScene = "QR_LIMIT_STR_SCENE"
+)
+
+// 临时二维码有效期限制，单位秒
+const (
+	DefaultExpireSeconds = 30
+	MaxExpireSeconds     = 2592000
+)
+
+// QRCode 二维码创建结果
+type QRCode struct {
+	Ticket        string `json:"ticket"`
+	ExpireSeconds int    `json:"expire_seconds"`
+	URL           string `json:"url"`
+}
+
+type scene struct {
+	SceneID  int64  `json:"scene_id,omitempty"`
+	SceneStr string `json:"scene_str,omitempty"`
+}
+
+type createRequest struct {
+	ExpireSeconds int    `json:"expire_seconds,omitempty"`
+	ActionName    string `json:"action_name"`
+	ActionInfo    struct {
+		Scene scene `json:"scene"`
+	} `json:"action_info"`
+}
+
+// CreateTemp 创建整型场景值的临时二维码，expireSeconds最大为30天，为0时使用默认的30秒
+func CreateTemp(svr *server.Server, sceneID int64, expireSeconds int) (*QRCode, error) {
+	return CreateTempContext(context.Background(), svr, sceneID, expireSeconds)
+}
+
+// CreateTempContext 创建整型场景值的临时二维码，expireSeconds最大为30天，为0时使用默认的30秒
+func CreateTempContext(ctx context.Context, svr *server.Server, sceneID int64, expireSeconds int) (*QRCode, error) {
+	request := createRequest{ExpireSeconds: expireSeconds, ActionName: actionScene}
+	request.ActionInfo.Scene.SceneID = sceneID
+	return create(ctx, svr, &request)
+}
+
+// CreateTempStr 创建字符串场景值的临时二维码，expireSeconds最大为30天
+func CreateTempStr(svr *server.Server, sceneStr string, expireSeconds int) (*QRCode, error) {
+	return CreateTempStrContext(context.Background(), svr, sceneStr, expireSeconds)
+}
+
+// CreateTempStrContext 创建字符串场景值的临时二维码，expireSeconds最大为30天
+func CreateTempStrContext(ctx context.Context, svr *server.Server, sceneStr string, expireSeconds int) (*QRCode, error) {
+	request := createRequest{ExpireSeconds: expireSeconds, ActionName: actionStrScene}
+	request.ActionInfo.Scene.SceneStr = sceneStr
+	return create(ctx, svr, &request)
+}
+
+// CreateLimit 创建整型场景值的永久二维码，sceneID取值1到100000
+func CreateLimit(svr *server.Server, sceneID int64) (*QRCode, error) {
+	return CreateLimitContext(context.Background(), svr, sceneID)
+}
+
+// CreateLimitContext 创建整型场景值的永久二维码，sceneID取值1到100000
+func CreateLimitContext(ctx context.Context, svr *server.Server, sceneID int64) (*QRCode, error) {
+	request := createRequest{ActionName: actionLimitScene}
+	request.ActionInfo.Scene.SceneID = sceneID
+	return create(ctx, svr, &request)
+}
+
+// CreateLimitStr 创建字符串场景值的永久二维码，sceneStr长度1到64
+func CreateLimitStr(svr *server.Server, sceneStr string) (*QRCode, error) {
+	return CreateLimitStrContext(context.Background(), svr, sceneStr)
+}
+
+// CreateLimitStrContext 创建字符串场景值的永久二维码，sceneStr长度1到64
+func CreateLimitStrContext(ctx context.Context, svr *server.Server, sceneStr string) (*QRCode, error) {
+	request := createRequest{ActionName: actionLimitStrScene}
+	request.ActionInfo.Scene.SceneStr = sceneStr
+	return create(ctx, svr, &request)
+}
+
+func create(ctx context.Context, svr *server.Server, request *createRequest) (*QRCode, error) {
+	result := new(QRCode)
+	if err := svr.Client().PostJSONContext(ctx, createPath, nil, request, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ShowURL 返回ticket对应的二维码图片地址
+func ShowURL(ticket string) string {
+	return ShowQRCodeURL + "?ticket=" + url.QueryEscape(ticket)
+}
+
+// Download 下载ticket对应的二维码图片写入w
+func Download(svr *server.Server, ticket string, w io.Writer) error {
+	return DownloadContext(context.Background(), svr, ticket, w)
+}
+
+// DownloadContext 下载ticket对应的二维码图片写入w
+func DownloadContext(ctx context.Context, svr *server.Server, ticket string, w io.Writer) error {
+	request, err := http.NewRequest(http.MethodGet, ShowURL(ticket), nil)
+	if err != nil {
+		return err
+	}
+	response, err := svr.Client().HTTPClient().Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("qrcode: showqrcode returned %s", response.Status)
+	}
+	_, err = io.Copy(w, response.Body)
+	return err
+}
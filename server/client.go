@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 微信接口域名
+const (
+	DefaultBaseURL = "https://api.weixin.qq.com"
+	// BackupBaseURL 容灾域名
+	BackupBaseURL = "https://api2.weixin.qq.com"
+)
+
+// DefaultTimeout 默认接口请求超时时间
+const DefaultTimeout = 10 * time.Second
+
+const jsonContentType = "application/json;charset=utf-8"
+
+// APIClient 微信接口调用客户端，自动附带AccessToken，AccessToken失效时刷新后重试一次
+type APIClient struct {
+	tokens     *TokenManager
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewAPIClient 创建接口客户端，tokens为nil时需要在参数中自行传入access_token
+func NewAPIClient(tokens *TokenManager) *APIClient {
+	client := new(APIClient)
+	client.tokens = tokens
+	client.httpClient = &http.Client{Timeout: DefaultTimeout}
+	client.baseURL = DefaultBaseURL
+	return client
+}
+
+// DefaultClient 不带AccessToken管理的默认客户端
+var DefaultClient = NewAPIClient(nil)
+
+// SetHTTPClient 设置底层http.Client，需在开始调用接口前设置
+func (client *APIClient) SetHTTPClient(httpClient *http.Client) {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: DefaultTimeout}
+	}
+	client.httpClient = httpClient
+}
+
+// HTTPClient 返回底层http.Client，可用于请求非接口域名的地址
+func (client *APIClient) HTTPClient() *http.Client {
+	return client.httpClient
+}
+
+// SetTimeout 设置接口请求超时时间，需在开始调用接口前设置
+func (client *APIClient) SetTimeout(timeout time.Duration) {
+	httpClient := *client.httpClient
+	httpClient.Timeout = timeout
+	client.httpClient = &httpClient
+}
+
+// SetBaseURL 设置接口域名，可用于切换容灾域名或指向本地测试服务，需在开始调用接口前设置
+func (client *APIClient) SetBaseURL(baseURL string) {
+	client.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// BaseURL 返回当前接口域名
+func (client *APIClient) BaseURL() string {
+	return client.baseURL
+}
+
+// Get 以GET方式调用接口，返回JSON解析到result
+func (client *APIClient) Get(path string, params url.Values, result interface{}) error {
+	return client.GetContext(context.Background(), path, params, result)
+}
+
+// GetContext 以GET方式调用接口，返回JSON解析到result
+func (client *APIClient) GetContext(ctx context.Context, path string, params url.Values, result interface{}) error {
+	req := &apiRequest{method: http.MethodGet, path: path, params: params}
+	return client.call(ctx, req, jsonResult(result))
+}
+
+// PostJSON 以POST方式提交JSON调用接口，返回JSON解析到result
+func (client *APIClient) PostJSON(path string, params url.Values, body interface{}, result interface{}) error {
+	return client.PostJSONContext(context.Background(), path, params, body, result)
+}
+
+// PostJSONContext 以POST方式提交JSON调用接口，返回JSON解析到result
+func (client *APIClient) PostJSONContext(ctx context.Context, path string, params url.Values, body interface{}, result interface{}) error {
+	req, err := newJSONRequest(path, params, body)
+	if err != nil {
+		return err
+	}
+	return client.call(ctx, req, jsonResult(result))
+}
+
+// Upload 以multipart/form-data方式上传文件，fields为附加的表单字段，返回JSON解析到result
+func (client *APIClient) Upload(path string, params url.Values, fieldName string, fileName string, file io.Reader, fields map[string]string, result interface{}) error {
+	return client.UploadContext(context.Background(), path, params, fieldName, fileName, file, fields, result)
+}
+
+// UploadContext 以multipart/form-data方式上传文件，fields为附加的表单字段，返回JSON解析到result
+func (client *APIClient) UploadContext(ctx context.Context, path string, params url.Values, fieldName string, fileName string, file io.Reader, fields map[string]string, result interface{}) error {
+	// 先完整读入内存，AccessToken失效重试时需要重新发送
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile(fieldName, fileName)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, file); err != nil {
+		return err
+	}
+	for key, value := range fields {
+		if err := writer.WriteField(key, value); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	req := &apiRequest{method: http.MethodPost, path: path, params: params}
+	req.content = buf.Bytes()
+	req.contentType = writer.FormDataContentType()
+	return client.call(ctx, req, jsonResult(result))
+}
+
+// Download 下载文件写入w，body不为nil时以POST方式提交JSON，返回响应头
+//
+// 接口返回JSON时若包含错误码则返回APIError，否则将JSON原样写入w
+func (client *APIClient) Download(path string, params url.Values, body interface{}, w io.Writer) (http.Header, error) {
+	return client.DownloadContext(context.Background(), path, params, body, w)
+}
+
+// DownloadContext 下载文件写入w，body不为nil时以POST方式提交JSON，返回响应头
+func (client *APIClient) DownloadContext(ctx context.Context, path string, params url.Values, body interface{}, w io.Writer) (http.Header, error) {
+	req := &apiRequest{method: http.MethodGet, path: path, params: params}
+	if body != nil {
+		var err error
+		req, err = newJSONRequest(path, params, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var header http.Header
+	err := client.call(ctx, req, func(response *http.Response) error {
+		contentType := response.Header.Get("Content-Type")
+		if strings.Contains(contentType, "json") || strings.HasPrefix(contentType, "text/plain") {
+			resultBytes, err := ioutil.ReadAll(response.Body)
+			if err != nil {
+				return err
+			}
+			if err := decodeResult(resultBytes, nil); err != nil {
+				return err
+			}
+			header = response.Header
+			_, err = w.Write(resultBytes)
+			return err
+		}
+		header = response.Header
+		_, err := io.Copy(w, response.Body)
+		return err
+	})
+	return header, err
+}
+
+// apiRequest 一次接口调用的请求内容，AccessToken失效重试时会重新发送
+type apiRequest struct {
+	method      string
+	path        string
+	params      url.Values
+	content     []byte
+	contentType string
+}
+
+func newJSONRequest(path string, params url.Values, body interface{}) (*apiRequest, error) {
+	req := &apiRequest{method: http.MethodPost, path: path, params: params}
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		req.content = content
+		req.contentType = jsonContentType
+	}
+	return req, nil
+}
+
+// jsonResult 返回解析JSON响应的处理函数
+func jsonResult(result interface{}) func(*http.Response) error {
+	return func(response *http.Response) error {
+		resultBytes, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return err
+		}
+		return decodeResult(resultBytes, result)
+	}
+}
+
+func (client *APIClient) call(ctx context.Context, req *apiRequest, handle func(*http.Response) error) error {
+	query := url.Values{}
+	for key, values := range req.params {
+		query[key] = values
+	}
+
+	// 参数中已传入access_token时不做自动管理
+	manageToken := client.tokens != nil && query.Get("access_token") == ""
+	var token string
+	if manageToken {
+		var err error
+		token, err = client.tokens.TokenContext(ctx)
+		if err != nil {
+			return err
+		}
+		query.Set("access_token", token)
+	}
+
+	err := client.do(ctx, req, query, handle)
+	if manageToken && IsTokenExpired(err) {
+		client.tokens.Invalidate(token)
+		token, err = client.tokens.TokenContext(ctx)
+		if err != nil {
+			return err
+		}
+		query.Set("access_token", token)
+		err = client.do(ctx, req, query, handle)
+	}
+	return err
+}
+
+func (client *APIClient) do(ctx context.Context, req *apiRequest, query url.Values, handle func(*http.Response) error) error {
+	requestURL := client.baseURL + req.path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if req.content != nil {
+		body = bytes.NewReader(req.content)
+	}
+	request, err := http.NewRequest(req.method, requestURL, body)
+	if err != nil {
+		return err
+	}
+	request = request.WithContext(ctx)
+	if req.contentType != "" {
+		request.Header.Set("Content-Type", req.contentType)
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	return handle(response)
+}
+
+// fetchAccessToken 获取AccessToken，不经过AccessToken管理
+func (client *APIClient) fetchAccessToken(ctx context.Context, appid string, appsecret string) (AccessTokenInfo, error) {
+	tokenInfo := AccessTokenInfo{}
+	params := url.Values{}
+	params.Set("grant_type", "client_credential")
+	params.Set("appid", appid)
+	params.Set("secret", appsecret)
+	req := &apiRequest{method: http.MethodGet, path: "/cgi-bin/token"}
+	err := client.do(ctx, req, params, jsonResult(&tokenInfo))
+	return tokenInfo, err
+}
+
+// decodeResult 解析接口返回的JSON，errcode不为0时返回APIError
+func decodeResult(resultBytes []byte, result interface{}) error {
+	apiErr := APIError{}
+	if err := json.Unmarshal(resultBytes, &apiErr); err != nil {
+		return err
+	}
+	if apiErr.ErrCode != ErrCodeOK {
+		return &apiErr
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resultBytes, result)
+}
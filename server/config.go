@@ -1,15 +1,7 @@
 package server
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"net/http"
-	"time"
-)
-
-const (
-	AccessTokenURL = "https://api.weixin.qq.com/cgi-bin/token?grant_type=client_credential&appid=%s&secret=%s"
+	"context"
 )
 
 type AccessTokenInfo struct {
@@ -17,34 +9,18 @@ type AccessTokenInfo struct {
 	ExpiresIn   int    `json:"expires_in,omitempty"`
 	ErrCode     int    `json:"errcode,omitempty"`
 	ErrMsg      string `json:"errmsg,omitempty"`
-	lastTime    time.Time
 }
 
-// GetAccessToken 向微信服务器获取AccessToken
+// GetAccessToken 向微信服务器获取AccessToken，获取失败时返回空字符串
 func GetAccessToken(appid string, appsecret string) string {
-	tokenInfo := AccessTokenInfo{}
-	url := fmt.Sprintf(AccessTokenURL, appid, appsecret)
-	response, err := http.Get(url)
-
+	tokenInfo, err := FetchAccessToken(appid, appsecret)
 	if err != nil {
 		return ""
 	}
+	return tokenInfo.AccessToken
+}
 
-	result, responseError := ioutil.ReadAll(response.Body)
-	if responseError != nil {
-		return ""
-	}
-
-	resultJSON := AccessTokenInfo{}
-	jsonError := json.Unmarshal(result, &resultJSON)
-	tokenInfo = resultJSON
-	tokenInfo.lastTime = time.Now()
-	if jsonError != nil {
-		return ""
-	}
-
-	if resultJSON.ErrCode == 0 && resultJSON.AccessToken != "" {
-		return tokenInfo.AccessToken
-	}
-	return ""
+// FetchAccessToken 向微信服务器获取AccessToken，失败时返回*APIError或网络错误
+func FetchAccessToken(appid string, appsecret string) (AccessTokenInfo, error) {
+	return DefaultClient.fetchAccessToken(context.Background(), appid, appsecret)
 }
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"coding.net/cherrysd/wxserver/message"
+)
+
+// ErrAlreadyReplied 同一请求重复回复
+var ErrAlreadyReplied = errors.New("wxserver: message already replied")
+
+// noReplyContent 不回复消息时返回给微信服务器的内容
+const noReplyContent = "success"
+
+// Context 单次消息请求的上下文
+type Context struct {
+	Writer  http.ResponseWriter
+	Request *http.Request
+	Msg     *message.RawMessage
+	server  *Server
+
+	mu      sync.Mutex
+	replied bool
+}
+
+type replyMessage interface {
+	Send(w http.ResponseWriter) error
+}
+
+func newContext(svr *Server, w http.ResponseWriter, r *http.Request, msg *message.RawMessage) *Context {
+	ctx := new(Context)
+	ctx.server = svr
+	ctx.Writer = w
+	ctx.Request = r
+	ctx.Msg = msg
+	return ctx
+}
+
+// Server 返回处理本次请求的实例
+func (ctx *Context) Server() *Server {
+	return ctx.server
+}
+
+// Context 返回本次请求的context.Context
+func (ctx *Context) Context() context.Context {
+	return ctx.Request.Context()
+}
+
+// Replied 是否已经回复过
+func (ctx *Context) Replied() bool {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.replied
+}
+
+// ReplyText 回复文本消息
+func (ctx *Context) ReplyText(content string) error {
+	text := new(message.Text)
+	text.ToUserName = ctx.Msg.FromUserName
+	text.FromUserName = ctx.Msg.ToUserName
+	text.Content = content
+	return ctx.reply(text)
+}
+
+// ReplyImage 回复图片消息
+func (ctx *Context) ReplyImage(mediaID string) error {
+	image := new(message.Image)
+	image.ToUserName = ctx.Msg.FromUserName
+	image.FromUserName = ctx.Msg.ToUserName
+	image.MediaID = mediaID
+	return ctx.reply(image)
+}
+
+// ReplyVoice 回复语音消息
+func (ctx *Context) ReplyVoice(mediaID string) error {
+	voice := new(message.Voice)
+	voice.ToUserName = ctx.Msg.FromUserName
+	voice.FromUserName = ctx.Msg.ToUserName
+	voice.MediaID = mediaID
+	return ctx.reply(voice)
+}
+
+// ReplyVideo 回复视频消息
+func (ctx *Context) ReplyVideo(mediaID string, title string, description string) error {
+	video := new(message.Video)
+	video.ToUserName = ctx.Msg.FromUserName
+	video.FromUserName = ctx.Msg.ToUserName
+	video.MediaID = mediaID
+	video.Title = title
+	video.Description = description
+	return ctx.reply(video)
+}
+
+// ReplyMusic 回复音乐消息，收发双方由上下文自动填写
+func (ctx *Context) ReplyMusic(music *message.Music) error {
+	reply := *music
+	reply.ToUserName = ctx.Msg.FromUserName
+	reply.FromUserName = ctx.Msg.ToUserName
+	return ctx.reply(&reply)
+}
+
+// DefaultShortenTimeout 回复图文消息时缩短链接的默认总超时，需留出被动回复5秒时限内的余量
+const DefaultShortenTimeout = time.Second
+
+// SetArticleURLShortener 设置回复图文消息时使用的链接缩短函数，timeout为全部文章的缩短总超时，为0时使用DefaultShortenTimeout
+//
+// 超时或缩短失败的文章保留原链接，错误交由ErrorHandle处理
+//
+// 只对Context.ReplyNews生效；处理器中直接调用message.Articles.Send(包括通过RegisterHandle注册的处理器)不会缩短链接，
+// 需要时在Send之前自行调用Articles.ShortenURLs
+func (svr *Server) SetArticleURLShortener(shortener message.URLShortener, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultShortenTimeout
+	}
+	svr.articleURLShortener = shortener
+	svr.shortenTimeout = timeout
+}
+
+// ReplyNews 回复图文消息，设置了链接缩短函数时先缩短文章链接
+func (ctx *Context) ReplyNews(articles []message.Article) error {
+	news := new(message.Articles)
+	news.ToUserName = ctx.Msg.FromUserName
+	news.FromUserName = ctx.Msg.ToUserName
+	news.Content = articles
+	if svr := ctx.server; svr != nil && svr.articleURLShortener != nil {
+		shortenCtx, cancel := context.WithTimeout(ctx.Context(), svr.shortenTimeout)
+		err := news.ShortenURLs(shortenCtx, svr.articleURLShortener)
+		cancel()
+		if err != nil {
+			svr.handleError(err, ctx.Request)
+		}
+	}
+	return ctx.reply(news)
+}
+
+// NoReply 不回复消息，直接返回success
+func (ctx *Context) NoReply() error {
+	if !ctx.markReplied() {
+		return ErrAlreadyReplied
+	}
+	_, err := fmt.Fprint(ctx.Writer, noReplyContent)
+	return err
+}
+
+func (ctx *Context) reply(msg replyMessage) error {
+	if !ctx.markReplied() {
+		return ErrAlreadyReplied
+	}
+	return msg.Send(ctx.Writer)
+}
+
+// markReplied 标记已回复，已经回复过时返回false
+func (ctx *Context) markReplied() bool {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.replied {
+		return false
+	}
+	ctx.replied = true
+	return true
+}
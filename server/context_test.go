@@ -0,0 +1,65 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"coding.net/cherrysd/wxserver/message"
+)
+
+func TestReplyNewsShortenURL(t *testing.T) {
+	svr := NewServer(testToken)
+	var handleErr error
+	svr.SetErrorHandle(func(err error, r *http.Request) { handleErr = err })
+	svr.SetArticleURLShortener(func(ctx context.Context, longURL string) (string, error) {
+		if longURL == "http://example.com/fail" {
+			return "", errors.New("shorten failed")
+		}
+		return "http://s.example.com/1", nil
+	}, 0)
+
+	articles := []message.Article{{Title: "a", URL: "http://example.com/a"}, {Title: "b", URL: "http://example.com/fail"}}
+	w := httptest.NewRecorder()
+	ctx := newContext(svr, w, httptest.NewRequest("POST", "/", nil), &message.RawMessage{})
+	if err := ctx.ReplyNews(articles); err != nil {
+		t.Fatalf("ReplyNews: %v", err)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "<Url>http://s.example.com/1</Url>") || !strings.Contains(body, "<Url>http://example.com/fail</Url>") {
+		t.Errorf("ReplyNews wrote %s", body)
+	}
+	if articles[0].URL != "http://example.com/a" {
+		t.Errorf("caller articles modified: %q", articles[0].URL)
+	}
+	if handleErr == nil {
+		t.Error("shorten error not passed to ErrorHandle")
+	}
+}
+
+func TestReplyNewsShortenTimeout(t *testing.T) {
+	svr := NewServer(testToken)
+	svr.SetErrorHandle(func(err error, r *http.Request) {})
+	svr.SetArticleURLShortener(func(ctx context.Context, longURL string) (string, error) {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}, 50*time.Millisecond)
+
+	w := httptest.NewRecorder()
+	ctx := newContext(svr, w, httptest.NewRequest("POST", "/", nil), &message.RawMessage{})
+	start := time.Now()
+	articles := []message.Article{{URL: "http://example.com/a"}, {URL: "http://example.com/b"}}
+	if err := ctx.ReplyNews(articles); err != nil {
+		t.Fatalf("ReplyNews: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("ReplyNews took %v, want about 50ms", elapsed)
+	}
+	if !strings.Contains(w.Body.String(), "<Url>http://example.com/b</Url>") {
+		t.Errorf("ReplyNews wrote %s", w.Body.String())
+	}
+}
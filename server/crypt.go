@@ -0,0 +1,99 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"coding.net/cherrysd/wxserver/crypter"
+	"coding.net/cherrysd/wxserver/util"
+)
+
+// EncryptMode 消息加解密方式
+type EncryptMode int
+
+// 消息加解密方式枚举
+const (
+	// PlainMode 明文模式
+	PlainMode EncryptMode = iota
+	// CompatibleMode 兼容模式，明文与密文消息均可处理
+	CompatibleMode
+	// SafeMode 安全模式，只处理密文消息
+	SafeMode
+)
+
+// ErrPlaintextRejected 安全模式下收到明文消息
+var ErrPlaintextRejected = errors.New("wxserver: plaintext message rejected in safe mode")
+
+// SetEncryptMode 设置消息加解密方式，非明文模式需要提供EncodingAESKey
+func (svr *Server) SetEncryptMode(mode EncryptMode, encodingAESKey string) error {
+	svr.encryptMode = mode
+	svr.encodingAESKey = encodingAESKey
+	return svr.resetCrypter()
+}
+
+func (svr *Server) resetCrypter() error {
+	svr.crypter = nil
+	if svr.encryptMode == PlainMode {
+		return nil
+	}
+	c, err := crypter.NewCrypter(svr.checkToken, svr.encodingAESKey, svr.appid)
+	if err != nil {
+		return err
+	}
+	svr.crypter = c
+	return nil
+}
+
+// encryptResponseWriter 缓存处理器写入的明文回复，在请求结束时加密后统一写出
+type encryptResponseWriter struct {
+	http.ResponseWriter
+	crypter *crypter.Crypter
+	nonce   string
+	status  int
+	buf     bytes.Buffer
+}
+
+func newEncryptResponseWriter(w http.ResponseWriter, c *crypter.Crypter, nonce string) *encryptResponseWriter {
+	ew := new(encryptResponseWriter)
+	ew.ResponseWriter = w
+	ew.crypter = c
+	ew.nonce = nonce
+	return ew
+}
+
+func (ew *encryptResponseWriter) WriteHeader(status int) {
+	if ew.status == 0 {
+		ew.status = status
+	}
+}
+
+func (ew *encryptResponseWriter) Write(b []byte) (int, error) {
+	return ew.buf.Write(b)
+}
+
+func (ew *encryptResponseWriter) flush() {
+	status := ew.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	body := ew.buf.Bytes()
+	// 空回复与"success"无需加密
+	if len(body) == 0 || string(body) == "success" {
+		ew.ResponseWriter.WriteHeader(status)
+		ew.ResponseWriter.Write(body)
+		return
+	}
+
+	timestamp := strconv.FormatInt(util.GetCurrTimeStamp(), 10)
+	encrypted, err := ew.crypter.EncryptMsg(body, timestamp, ew.nonce)
+	if err != nil {
+		log.Println("Encrypt Response Message Error")
+		ew.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	ew.ResponseWriter.WriteHeader(status)
+	ew.ResponseWriter.Write(encrypted)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 常见的微信接口全局返回码
+const (
+	ErrCodeSystemBusy         = -1
+	ErrCodeOK                 = 0
+	ErrCodeInvalidCredential  = 40001
+	ErrCodeInvalidGrantType   = 40002
+	ErrCodeInvalidOpenID      = 40003
+	ErrCodeInvalidMediaType   = 40004
+	ErrCodeInvalidMediaID     = 40007
+	ErrCodeInvalidMessageType = 40008
+	ErrCodeInvalidAppID       = 40013
+	ErrCodeInvalidAccessToken = 40014
+	ErrCodeInvalidButtonCount = 40016
+	ErrCodeInvalidCode        = 40029
+	ErrCodeInvalidIP          = 40164
+	ErrCodeMissingAccessToken = 41001
+	ErrCodeAccessTokenExpired = 42001
+	ErrCodeRequireSubscribe   = 43004
+	ErrCodeAPIQuotaExceeded   = 45009
+	ErrCodeResponseOutOfTime  = 45015
+	ErrCodeAPIUnauthorized    = 48001
+	ErrCodeUserUnauthorized   = 50001
+)
+
+// APIError 微信接口调用返回的错误
+type APIError struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("wxserver: errcode %d: %s", e.ErrCode, e.ErrMsg)
+}
+
+// IsTokenExpired 判断错误是否由AccessToken失效引起
+func IsTokenExpired(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrCode {
+	case ErrCodeInvalidCredential, ErrCodeInvalidAccessToken, ErrCodeAccessTokenExpired:
+		return true
+	}
+	return false
+}
+
+// IsErrCode 判断错误是否为指定返回码的APIError
+func IsErrCode(err error, errCode int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.ErrCode == errCode
+}
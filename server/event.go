@@ -0,0 +1,278 @@
+package server
+
+import (
+	"coding.net/cherrysd/wxserver/message"
+)
+
+// 事件类型对应的处理器类型
+var eventHandleTypes = map[string]HandleType{
+	message.EventSubscribe:   SubscribeHandle,
+	message.EventUnsubscribe: UnsubscribeHandle,
+	message.EventScan:        ScanHandle,
+	message.EventLocation:    LocationEventHandle,
+	message.EventClick:       ClickHandle,
+	message.EventView:        ViewHandle,
+
+	message.EventTemplateSendJobFinish: TemplateSendJobFinishHandle,
+
+	message.EventScancodePush:    ScanCodeHandle,
+	message.EventScancodeWaitMsg: ScanCodeHandle,
+	message.EventPicSysPhoto:     SendPicsHandle,
+	message.EventPicPhotoOrAlbum: SendPicsHandle,
+	message.EventPicWeixin:       SendPicsHandle,
+	message.EventLocationSelect:  LocationSelectHandle,
+}
+
+// OnEvent 注册事件处理器，未注册对应事件类型处理器的事件都交由它处理
+func (svr *Server) OnEvent(handle func(*Context, *message.Event)) {
+	svr.setHandle(EventHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.Event)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.Event = msg.Event
+		event.EventKey = msg.EventKey
+		event.Longitude = msg.Longitude
+		event.Latitude = msg.Latitude
+		event.Ticket = msg.Ticket
+		event.Precision = msg.Precision
+		event.ScanCodeInfo = msg.ScanCodeInfo
+		event.SendPicsInfo = msg.SendPicsInfo
+		event.SendLocationInfo = msg.SendLocationInfo
+		handle(ctx, event)
+	})
+}
+
+// OnSubscribe 注册关注事件处理器
+func (svr *Server) OnSubscribe(handle func(*Context, *message.SubscribeEvent)) {
+	svr.setHandle(SubscribeHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.SubscribeEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.EventKey = msg.EventKey
+		event.Ticket = msg.Ticket
+		event.SceneValue = message.SceneValue(msg.EventKey)
+		handle(ctx, event)
+	})
+}
+
+// OnScene 按二维码场景值注册处理器，用户扫码关注(qrscene_前缀已去除)或已关注用户扫码时调用，优先于OnSubscribe与OnScan
+func (svr *Server) OnScene(sceneValue string, handle func(*Context, *message.SceneEvent)) {
+	if handle != nil {
+		svr.sceneHandleMap[sceneValue] = func(ctx *Context) {
+			msg := ctx.Msg
+			event := new(message.SceneEvent)
+			event.FromUserName = msg.FromUserName
+			event.ToUserName = msg.ToUserName
+			event.CreateTime = msg.CreateTime
+			event.Event = msg.Event
+			event.SceneValue = message.SceneValue(msg.EventKey)
+			event.Ticket = msg.Ticket
+			handle(ctx, event)
+		}
+	}
+}
+
+// OnUnsubscribe 注册取消关注事件处理器
+func (svr *Server) OnUnsubscribe(handle func(*Context, *message.UnsubscribeEvent)) {
+	svr.setHandle(UnsubscribeHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.UnsubscribeEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		handle(ctx, event)
+	})
+}
+
+// OnScan 注册已关注用户扫码事件处理器
+func (svr *Server) OnScan(handle func(*Context, *message.ScanEvent)) {
+	svr.setHandle(ScanHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.ScanEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.EventKey = msg.EventKey
+		event.Ticket = msg.Ticket
+		event.SceneValue = message.SceneValue(msg.EventKey)
+		handle(ctx, event)
+	})
+}
+
+// OnLocationEvent 注册上报地理位置事件处理器
+func (svr *Server) OnLocationEvent(handle func(*Context, *message.LocationEvent)) {
+	svr.setHandle(LocationEventHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.LocationEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.Latitude = msg.Latitude
+		event.Longitude = msg.Longitude
+		event.Precision = msg.Precision
+		handle(ctx, event)
+	})
+}
+
+// OnClick 注册点击菜单事件处理器，未按EventKey注册处理器的点击事件交由它处理
+func (svr *Server) OnClick(handle func(*Context, *message.ClickEvent)) {
+	svr.setHandle(ClickHandle, handle != nil, clickHandle(handle))
+}
+
+// OnClickKey 按菜单EventKey注册点击事件处理器，优先于OnClick
+//
+// 与OnMenuKey共用同一份EventKey注册表，同一EventKey后注册的处理器生效
+func (svr *Server) OnClickKey(eventKey string, handle func(*Context, *message.ClickEvent)) {
+	if handle != nil {
+		click := clickHandle(handle)
+		svr.OnMenuKey(eventKey, func(ctx *Context, _ *message.MenuEvent) { click(ctx) })
+	}
+}
+
+func clickHandle(handle func(*Context, *message.ClickEvent)) HandleFunc {
+	return func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.ClickEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.EventKey = msg.EventKey
+		handle(ctx, event)
+	}
+}
+
+// OnView 注册点击菜单跳转链接事件处理器
+func (svr *Server) OnView(handle func(*Context, *message.ViewEvent)) {
+	svr.setHandle(ViewHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.ViewEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.EventKey = msg.EventKey
+		event.MenuID = msg.MenuID
+		handle(ctx, event)
+	})
+}
+
+// OnScanCode 注册菜单扫码事件处理器，处理scancode_push与scancode_waitmsg
+func (svr *Server) OnScanCode(handle func(*Context, *message.ScanCodeEvent)) {
+	svr.setHandle(ScanCodeHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.ScanCodeEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.Event = msg.Event
+		event.EventKey = msg.EventKey
+		if msg.ScanCodeInfo != nil {
+			event.ScanCodeInfo = *msg.ScanCodeInfo
+		}
+		handle(ctx, event)
+	})
+}
+
+// OnSendPics 注册菜单发图事件处理器，处理pic_sysphoto、pic_photo_or_album与pic_weixin
+func (svr *Server) OnSendPics(handle func(*Context, *message.SendPicsEvent)) {
+	svr.setHandle(SendPicsHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.SendPicsEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.Event = msg.Event
+		event.EventKey = msg.EventKey
+		if msg.SendPicsInfo != nil {
+			event.SendPicsInfo = *msg.SendPicsInfo
+		}
+		handle(ctx, event)
+	})
+}
+
+// OnLocationSelect 注册菜单发送位置事件处理器
+func (svr *Server) OnLocationSelect(handle func(*Context, *message.LocationSelectEvent)) {
+	svr.setHandle(LocationSelectHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.LocationSelectEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.EventKey = msg.EventKey
+		if msg.SendLocationInfo != nil {
+			event.SendLocationInfo = *msg.SendLocationInfo
+		}
+		handle(ctx, event)
+	})
+}
+
+// OnTemplateSendJobFinish 注册模板消息发送结果事件处理器
+func (svr *Server) OnTemplateSendJobFinish(handle func(*Context, *message.TemplateSendJobFinishEvent)) {
+	svr.setHandle(TemplateSendJobFinishHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		event := new(message.TemplateSendJobFinishEvent)
+		event.FromUserName = msg.FromUserName
+		event.ToUserName = msg.ToUserName
+		event.CreateTime = msg.CreateTime
+		event.MsgID = msg.MsgID
+		event.Status = msg.Status
+		handle(ctx, event)
+	})
+}
+
+// 按菜单EventKey路由的事件类型
+var menuEventTypes = map[string]bool{
+	message.EventClick:           true,
+	message.EventScancodePush:    true,
+	message.EventScancodeWaitMsg: true,
+	message.EventPicSysPhoto:     true,
+	message.EventPicPhotoOrAlbum: true,
+	message.EventPicWeixin:       true,
+	message.EventLocationSelect:  true,
+}
+
+// OnMenuKey 按菜单EventKey注册菜单事件处理器，处理点击、扫码、发图、发送位置事件，优先于按事件类型注册的处理器
+func (svr *Server) OnMenuKey(eventKey string, handle func(*Context, *message.MenuEvent)) {
+	if handle != nil {
+		svr.menuKeyHandleMap[eventKey] = func(ctx *Context) {
+			msg := ctx.Msg
+			event := new(message.MenuEvent)
+			event.FromUserName = msg.FromUserName
+			event.ToUserName = msg.ToUserName
+			event.CreateTime = msg.CreateTime
+			event.Event = msg.Event
+			event.EventKey = msg.EventKey
+			event.ScanCodeInfo = msg.ScanCodeInfo
+			event.SendPicsInfo = msg.SendPicsInfo
+			event.SendLocationInfo = msg.SendLocationInfo
+			handle(ctx, event)
+		}
+	}
+}
+
+// dispatchEvent 按事件类型分发事件，依次查找EventKey或场景值、事件类型与通用事件处理器
+func (svr *Server) dispatchEvent(ctx *Context) {
+	msg := ctx.Msg
+	if (msg.Event == message.EventSubscribe || msg.Event == message.EventScan) && msg.EventKey != "" {
+		if handle := svr.sceneHandleMap[message.SceneValue(msg.EventKey)]; handle != nil {
+			handle(ctx)
+			return
+		}
+	}
+	if menuEventTypes[msg.Event] {
+		if handle := svr.menuKeyHandleMap[msg.EventKey]; handle != nil {
+			handle(ctx)
+			return
+		}
+	}
+	if handle := svr.handleMap[eventHandleTypes[msg.Event]]; handle != nil {
+		handle(ctx)
+		return
+	}
+	if handle := svr.handleMap[EventHandle]; handle != nil {
+		handle(ctx)
+	}
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"coding.net/cherrysd/wxserver/message"
+)
+
+// HandleFunc 消息处理器，通过Context.Msg访问原始消息
+type HandleFunc func(*Context)
+
+// 普通消息类型对应的处理器类型
+var messageHandleTypes = map[message.Type]HandleType{
+	message.TextMsg:       TextHandle,
+	message.ImageMsg:      ImageHandle,
+	message.VoiceMsg:      VoiceHandle,
+	message.VideoMsg:      VideoHandle,
+	message.ShortvideoMsg: ShortVideoHandle,
+	message.LocationMsg:   LocationHandle,
+	message.LinkMsg:       LinkHandle,
+}
+
+// OnRaw 注册原始消息处理器，注册后其他处理器不再生效
+func (svr *Server) OnRaw(handle func(*Context, *message.RawMessage)) {
+	svr.setHandle(RawHandle, handle != nil, func(ctx *Context) {
+		handle(ctx, ctx.Msg)
+	})
+}
+
+// OnText 注册文本消息处理器
+func (svr *Server) OnText(handle func(*Context, *message.Text)) {
+	svr.setHandle(TextHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		text := new(message.Text)
+		text.FromUserName = msg.FromUserName
+		text.ToUserName = msg.ToUserName
+		text.Content = msg.Content
+		text.CreateTime = msg.CreateTime
+		text.MsgID = msg.MsgID
+		handle(ctx, text)
+	})
+}
+
+// OnImage 注册图片消息处理器
+func (svr *Server) OnImage(handle func(*Context, *message.Image)) {
+	svr.setHandle(ImageHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		image := new(message.Image)
+		image.FromUserName = msg.FromUserName
+		image.ToUserName = msg.ToUserName
+		image.MediaID = msg.MediaID
+		image.CreateTime = msg.CreateTime
+		image.MsgID = msg.MsgID
+		handle(ctx, image)
+	})
+}
+
+// OnVoice 注册语音消息处理器
+func (svr *Server) OnVoice(handle func(*Context, *message.Voice)) {
+	svr.setHandle(VoiceHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		voice := new(message.Voice)
+		voice.FromUserName = msg.FromUserName
+		voice.ToUserName = msg.ToUserName
+		voice.MediaID = msg.MediaID
+		voice.CreateTime = msg.CreateTime
+		voice.MsgID = msg.MsgID
+		handle(ctx, voice)
+	})
+}
+
+// OnVideo 注册视频消息处理器
+func (svr *Server) OnVideo(handle func(*Context, *message.Video)) {
+	svr.setHandle(VideoHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		video := new(message.Video)
+		video.FromUserName = msg.FromUserName
+		video.ToUserName = msg.ToUserName
+		video.MediaID = msg.MediaID
+		video.ThumbMediaID = msg.ThumbMediaID
+		video.CreateTime = msg.CreateTime
+		video.MsgID = msg.MsgID
+		video.Title = msg.Title
+		video.Description = msg.Description
+		handle(ctx, video)
+	})
+}
+
+// OnShortVideo 注册小视频消息处理器
+func (svr *Server) OnShortVideo(handle func(*Context, *message.ShortVideo)) {
+	svr.setHandle(ShortVideoHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		shortVideo := new(message.ShortVideo)
+		shortVideo.FromUserName = msg.FromUserName
+		shortVideo.ToUserName = msg.ToUserName
+		shortVideo.MediaID = msg.MediaID
+		shortVideo.ThumbMediaID = msg.ThumbMediaID
+		shortVideo.CreateTime = msg.CreateTime
+		shortVideo.MsgID = msg.MsgID
+		handle(ctx, shortVideo)
+	})
+}
+
+// OnLocation 注册地理位置消息处理器
+func (svr *Server) OnLocation(handle func(*Context, *message.Location)) {
+	svr.setHandle(LocationHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		location := new(message.Location)
+		location.FromUserName = msg.FromUserName
+		location.ToUserName = msg.ToUserName
+		location.CreateTime = msg.CreateTime
+		location.MsgID = msg.MsgID
+		location.LocationX = msg.LocationX
+		location.LocationY = msg.LocationY
+		location.Scale = msg.Scale
+		location.Label = msg.Label
+		handle(ctx, location)
+	})
+}
+
+// OnLink 注册链接消息处理器
+func (svr *Server) OnLink(handle func(*Context, *message.Link)) {
+	svr.setHandle(LinkHandle, handle != nil, func(ctx *Context) {
+		msg := ctx.Msg
+		link := new(message.Link)
+		link.FromUserName = msg.FromUserName
+		link.ToUserName = msg.ToUserName
+		link.CreateTime = msg.CreateTime
+		link.MsgID = msg.MsgID
+		link.Title = msg.Title
+		link.Description = msg.Description
+		link.URL = msg.URL
+		handle(ctx, link)
+	})
+}
+
+// setHandle 保存处理器，valid为false(用户传入nil)时忽略
+func (svr *Server) setHandle(handleType HandleType, valid bool, handle HandleFunc) {
+	if valid {
+		svr.handleMap[handleType] = handle
+	}
+}
+
+// dispatch 按消息类型分发给已注册的处理器
+func (svr *Server) dispatch(ctx *Context) {
+	if handle := svr.handleMap[RawHandle]; handle != nil {
+		handle(ctx)
+		return
+	}
+	if ctx.Msg.MsgType == message.EventMsg {
+		svr.dispatchEvent(ctx)
+		return
+	}
+	if handle := svr.handleMap[messageHandleTypes[ctx.Msg.MsgType]]; handle != nil {
+		handle(ctx)
+	}
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+
+	"coding.net/cherrysd/wxserver/message"
+)
+
+const testToken = "token"
+
+// testSignature 按微信规则计算signature，与util中的实现相互独立
+func testSignature(timestamp string, nonce string) string {
+	strs := []string{testToken, timestamp, nonce}
+	sort.Strings(strs)
+	return fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(strs, ""))))
+}
+
+// signedQuery 构造带有signature的回调参数
+func signedQuery(timestamp string, nonce string) url.Values {
+	query := url.Values{}
+	query.Set("signature", testSignature(timestamp, nonce))
+	query.Set("timestamp", timestamp)
+	query.Set("nonce", nonce)
+	return query
+}
+
+// newSignedRequest 构造带有signature的微信服务器推送请求
+func newSignedRequest(body string) *http.Request {
+	query := signedQuery("1357290913", "nonce")
+	return httptest.NewRequest(http.MethodPost, "/?"+query.Encode(), strings.NewReader(body))
+}
+
+func TestOnShortVideo(t *testing.T) {
+	svr := NewServer(testToken)
+	svr.SetTimestampWindow(0)
+
+	var got *message.ShortVideo
+	svr.OnShortVideo(func(ctx *Context, msg *message.ShortVideo) {
+		got = msg
+		ctx.NoReply()
+	})
+	svr.OnVideo(func(ctx *Context, msg *message.Video) {
+		t.Error("OnVideo handler called for shortvideo message")
+	})
+
+	w := httptest.NewRecorder()
+	svr.ServeHTTP(w, newSignedRequest(`<xml>
+<ToUserName><![CDATA[toUser]]></ToUserName>
+<FromUserName><![CDATA[fromUser]]></FromUserName>
+<CreateTime>1357290913</CreateTime>
+<MsgType><![CDATA[shortvideo]]></MsgType>
+<MediaId><![CDATA[media_id]]></MediaId>
+<ThumbMediaId><![CDATA[thumb_media_id]]></ThumbMediaId>
+<MsgId>1234567890123456</MsgId>
+</xml>`))
+
+	if got == nil {
+		t.Fatal("OnShortVideo handler not called")
+	}
+	want := message.ShortVideo{
+		ToUserName:   "toUser",
+		FromUserName: "fromUser",
+		CreateTime:   1357290913,
+		MediaID:      "media_id",
+		ThumbMediaID: "thumb_media_id",
+		MsgID:        1234567890123456,
+	}
+	if *got != want {
+		t.Errorf("ShortVideo = %+v, want %+v", *got, want)
+	}
+	if w.Code != http.StatusOK || w.Body.String() != "success" {
+		t.Errorf("response = %d %q, want 200 \"success\"", w.Code, w.Body.String())
+	}
+}
@@ -1,25 +1,36 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"net/http"
-	"strings"
 
+	"coding.net/cherrysd/wxserver/crypter"
 	"coding.net/cherrysd/wxserver/message"
-	"coding.net/cherrysd/wxserver/util"
-	"encoding/json"
 	"time"
 )
 
 // Server 微信后台实例
 type Server struct {
-	checkToken string
-	appid      string
-	appsecret  string
-	tokenInfo  AccessTokenInfo
-	handleMap  map[HandleType]interface{}
+	checkToken       string
+	appid            string
+	appsecret        string
+	tokenManager     *TokenManager
+	client           *APIClient
+	handleMap        map[HandleType]HandleFunc
+	menuKeyHandleMap map[string]HandleFunc
+	sceneHandleMap   map[string]HandleFunc
+	encryptMode      EncryptMode
+	encodingAESKey   string
+	crypter          *crypter.Crypter
+
+	timestampWindow time.Duration
+	errorHandle     ErrorHandle
+
+	articleURLShortener message.URLShortener
+	shortenTimeout      time.Duration
 }
 
 // HandleType 消息处理器类型
@@ -33,14 +44,36 @@ const (
 	VoiceHandle      = "VoiceHandle"
 	VideoHandle      = "VideoHandle"
 	ShortVideoHandle = "ShortVideoHandle"
+	LocationHandle   = "LocationHandle"
+	LinkHandle       = "LinkHandle"
 	EventHandle      = "EventHandle"
+
+	// 按事件类型区分的处理器，未注册时交由EventHandle处理
+	SubscribeHandle     = "SubscribeHandle"
+	UnsubscribeHandle   = "UnsubscribeHandle"
+	ScanHandle          = "ScanHandle"
+	LocationEventHandle = "LocationEventHandle"
+	ClickHandle         = "ClickHandle"
+	ViewHandle          = "ViewHandle"
+
+	TemplateSendJobFinishHandle = "TemplateSendJobFinishHandle"
+
+	ScanCodeHandle       = "ScanCodeHandle"
+	SendPicsHandle       = "SendPicsHandle"
+	LocationSelectHandle = "LocationSelectHandle"
 )
 
 // NewServer 创建底层服务实例
 func NewServer(checkToken string) *Server {
 	newServer := new(Server)
 	newServer.checkToken = checkToken
-	newServer.handleMap = make(map[HandleType]interface{})
+	newServer.handleMap = make(map[HandleType]HandleFunc)
+	newServer.menuKeyHandleMap = make(map[string]HandleFunc)
+	newServer.sceneHandleMap = make(map[string]HandleFunc)
+	newServer.timestampWindow = DefaultTimestampWindow
+	newServer.tokenManager = NewTokenManager("", "")
+	newServer.client = NewAPIClient(newServer.tokenManager)
+	newServer.tokenManager.SetClient(newServer.client)
 	return newServer
 }
 
@@ -48,202 +81,193 @@ func (svr *Server) init() {
 	log.Println("Server Init")
 }
 
+// GetAccessToken 获取AccessToken，获取失败时返回空字符串
 func (svr *Server) GetAccessToken() string {
-	if svr.tokenInfo.ErrCode != 0 || time.Now().Sub(svr.tokenInfo.lastTime).Seconds() > float64(svr.tokenInfo.ExpiresIn) {
-		//token超时了
-		if svr.appid == "" || svr.appsecret == "" {
-			return ""
-		}
-		svr.updateAccessToken(svr.appid, svr.appsecret)
+	token, err := svr.AccessToken()
+	if err != nil {
+		log.Println("Get Access Token Error", err)
+		return ""
 	}
-	return svr.tokenInfo.AccessToken
+	return token
 }
 
-func (svr *Server) updateAccessToken(appid string, appsecret string) {
-	svr.tokenInfo = AccessTokenInfo{}
-	url := fmt.Sprintf(AccessTokenURL, appid, appsecret)
-	response, err := http.Get(url)
-
-	if err != nil {
-		return
-	}
+// AccessToken 获取AccessToken，获取失败时返回错误
+func (svr *Server) AccessToken() (string, error) {
+	return svr.tokenManager.Token()
+}
 
-	result, responseError := ioutil.ReadAll(response.Body)
-	if responseError != nil {
-		return
-	}
+// AccessTokenContext 获取AccessToken，获取失败时返回错误
+func (svr *Server) AccessTokenContext(ctx context.Context) (string, error) {
+	return svr.tokenManager.TokenContext(ctx)
+}
 
-	resultJSON := AccessTokenInfo{}
-	jsonError := json.Unmarshal(result, &resultJSON)
-	svr.tokenInfo = resultJSON
-	svr.tokenInfo.lastTime = time.Now()
-	if jsonError != nil {
-		return
-	}
+// Client 返回使用本实例AccessToken的接口客户端
+func (svr *Server) Client() *APIClient {
+	return svr.client
+}
 
-	if resultJSON.ErrCode == 0 && resultJSON.AccessToken != "" {
-		return
-	}
-	return
+// TokenManager 返回AccessToken管理器，可用于设置存储与分布式锁
+func (svr *Server) TokenManager() *TokenManager {
+	return svr.tokenManager
 }
 
 // SetAppInfo 设置appid与appsecret
 func (svr *Server) SetAppInfo(appid string, appsecret string) {
 	svr.appid = appid
 	svr.appsecret = appsecret
+	svr.tokenManager.setAppInfo(appid, appsecret)
+	if err := svr.resetCrypter(); err != nil {
+		log.Println("Reset Crypter Error", err)
+	}
 }
 
 // AppHandle 微信公众号消息入口
 func (svr *Server) serverHandle(w http.ResponseWriter, r *http.Request) {
 	contentBytes, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		log.Println("Read Body Content Error")
+		svr.handleError(err, r)
+		return
+	}
+
+	query := r.URL.Query()
+	if svr.crypter != nil && query.Get("encrypt_type") == "aes" {
+		timestamp := query.Get("timestamp")
+		nonce := query.Get("nonce")
+		contentBytes, err = svr.crypter.DecryptMsg(query.Get("msg_signature"), timestamp, nonce, contentBytes)
+		if err != nil {
+			svr.handleError(err, r)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		ew := newEncryptResponseWriter(w, svr.crypter, nonce)
+		defer ew.flush()
+		w = ew
+	} else if svr.encryptMode == SafeMode {
+		svr.handleError(ErrPlaintextRejected, r)
+		w.WriteHeader(http.StatusForbidden)
 		return
 	}
+
 	var requestMsg message.RawMessage
 	requestMsg, err = message.ParseMsg(contentBytes)
 	if err != nil {
-		log.Println("Parse Request Message Error")
+		svr.handleError(err, r)
 		return
 	}
 
-	if svr.handleMap[RawHandle] == nil {
-		svr.defaultMessageHandle(requestMsg, w)
-	} else {
-		handleFunc := svr.handleMap[RawHandle].(func(message.RawMessage, http.ResponseWriter))
-		handleFunc(requestMsg, w)
-	}
+	svr.dispatch(newContext(svr, w, r, &requestMsg))
 }
 
 // ConnectServer 与wx公众号第一次连接，给腾讯做校验使用
 func (svr *Server) ConnectServer(w http.ResponseWriter, r *http.Request) {
-	r.ParseForm()
-	timestamp := strings.Join(r.Form["timestamp"], "")
-	nonce := strings.Join(r.Form["nonce"], "")
-	signatureIn := strings.Join(r.Form["signature"], "")
-	if util.CheckSignature(svr.checkToken, timestamp, nonce, signatureIn) {
-		echostr := strings.Join(r.Form["echostr"], "")
-		fmt.Fprintf(w, echostr)
-	} else {
-		fmt.Fprintf(w, "")
-	}
-}
-
-func (svr *Server) defaultMessageHandle(msg message.RawMessage, w http.ResponseWriter) {
-	switch msg.MsgType {
-	case message.TextMsg:
-		if svr.handleMap[TextHandle] != nil {
-			text := new(message.Text)
-			text.FromUserName = msg.FromUserName
-			text.ToUserName = msg.ToUserName
-			text.Content = msg.Content
-			text.CreateTime = msg.CreateTime
-			text.MsgID = msg.MsgID
-			handleFunc := svr.handleMap[TextHandle].(func(*message.Text, http.ResponseWriter))
-			handleFunc(text, w)
-		}
-	case message.ImageMsg:
-		if svr.handleMap[ImageHandle] != nil {
-			image := new(message.Image)
-			image.FromUserName = msg.FromUserName
-			image.ToUserName = msg.ToUserName
-			image.MediaID = msg.MediaID
-			image.CreateTime = msg.CreateTime
-			image.MsgID = msg.MsgID
-			handleFunc := svr.handleMap[ImageHandle].(func(*message.Image, http.ResponseWriter))
-			handleFunc(image, w)
-		}
-	case message.VoiceMsg:
-		if svr.handleMap[VoiceHandle] != nil {
-			voice := new(message.Voice)
-			voice.FromUserName = msg.FromUserName
-			voice.ToUserName = msg.ToUserName
-			voice.MediaID = msg.MediaID
-			voice.CreateTime = msg.CreateTime
-			voice.MsgID = msg.MsgID
-			handleFunc := svr.handleMap[VoiceHandle].(func(*message.Voice, http.ResponseWriter))
-			handleFunc(voice, w)
-		}
-	case message.ShortvideoMsg:
-		if svr.handleMap[ShortVideoHandle] != nil {
-			//todo
-		}
-	case message.VideoMsg:
-		if svr.handleMap[VideoHandle] != nil {
-			video := new(message.Video)
-			video.FromUserName = msg.FromUserName
-			video.ToUserName = msg.ToUserName
-			video.MediaID = msg.MediaID
-			video.CreateTime = msg.CreateTime
-			video.MsgID = msg.MsgID
-			video.Title = msg.Title
-			video.Description = msg.Description
-			handleFunc := svr.handleMap[VideoHandle].(func(*message.Video, http.ResponseWriter))
-			handleFunc(video, w)
-		}
-	case message.EventMsg:
-		if svr.handleMap[EventHandle] != nil {
-			event := new(message.Event)
-			event.FromUserName = msg.FromUserName
-			event.ToUserName = msg.ToUserName
-			event.CreateTime = msg.CreateTime
-			event.Event = msg.Event
-			event.EventKey = msg.Event
-			event.Longitude = msg.Longitude
-			event.Latitude = msg.Latitude
-			event.Ticket = msg.Ticket
-			event.Precision = msg.Precision
-			handleFunc := svr.handleMap[EventHandle].(func(*message.Event, http.ResponseWriter))
-			handleFunc(event, w)
-		}
+	if err := svr.VerifyRequest(r); err != nil {
+		svr.handleError(err, r)
+		fmt.Fprint(w, "")
+		return
 	}
+	fmt.Fprint(w, r.URL.Query().Get("echostr"))
 }
 
-// RegisterHandle 注册消息处理器
-func (svr *Server) RegisterHandle(handleType HandleType, handle interface{}) {
-	// 加入HandleMap之前做个类型检查
+// RegisterHandle 注册消息处理器，处理器签名与handleType不匹配时返回错误
+//
+// Deprecated: 使用OnText、OnEvent等类型安全的注册方法
+func (svr *Server) RegisterHandle(handleType HandleType, handle interface{}) error {
+	ok := false
 	switch handleType {
 	case RawHandle:
-		handleFunc := handle.(func(message.RawMessage, http.ResponseWriter))
-		if handleFunc != nil {
-			svr.handleMap[handleType] = handle
+		var handleFunc func(message.RawMessage, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(message.RawMessage, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnRaw(func(ctx *Context, msg *message.RawMessage) { handleFunc(*msg, ctx.Writer) })
 		}
 	case TextHandle:
-		handleFunc := handle.(func(*message.Text, http.ResponseWriter))
-		if handleFunc != nil {
-			svr.handleMap[handleType] = handle
+		var handleFunc func(*message.Text, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.Text, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnText(func(ctx *Context, msg *message.Text) { handleFunc(msg, ctx.Writer) })
 		}
 	case ImageHandle:
-		handleFunc := handle.(func(*message.Image, http.ResponseWriter))
-		if handleFunc != nil {
-			svr.handleMap[handleType] = handle
+		var handleFunc func(*message.Image, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.Image, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnImage(func(ctx *Context, msg *message.Image) { handleFunc(msg, ctx.Writer) })
 		}
 	case VoiceHandle:
-		handleFunc := handle.(func(*message.Voice, http.ResponseWriter))
-		if handleFunc != nil {
-			svr.handleMap[handleType] = handle
+		var handleFunc func(*message.Voice, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.Voice, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnVoice(func(ctx *Context, msg *message.Voice) { handleFunc(msg, ctx.Writer) })
 		}
 	case ShortVideoHandle:
-		handleFunc := handle.(func(*message.ShortVideo, http.ResponseWriter))
-		if handleFunc != nil {
-			svr.handleMap[handleType] = handle
+		var handleFunc func(*message.ShortVideo, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.ShortVideo, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnShortVideo(func(ctx *Context, msg *message.ShortVideo) { handleFunc(msg, ctx.Writer) })
 		}
 	case VideoHandle:
-		handleFunc := handle.(func(*message.Video, http.ResponseWriter))
-		if handleFunc != nil {
-			svr.handleMap[handleType] = handle
+		var handleFunc func(*message.Video, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.Video, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnVideo(func(ctx *Context, msg *message.Video) { handleFunc(msg, ctx.Writer) })
+		}
+	case LocationHandle:
+		var handleFunc func(*message.Location, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.Location, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnLocation(func(ctx *Context, msg *message.Location) { handleFunc(msg, ctx.Writer) })
+		}
+	case LinkHandle:
+		var handleFunc func(*message.Link, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.Link, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnLink(func(ctx *Context, msg *message.Link) { handleFunc(msg, ctx.Writer) })
 		}
 	case EventHandle:
-		handleFunc := handle.(func(*message.Event, http.ResponseWriter))
-		if handleFunc != nil {
-			svr.handleMap[handleType] = handle
+		var handleFunc func(*message.Event, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.Event, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnEvent(func(ctx *Context, msg *message.Event) { handleFunc(msg, ctx.Writer) })
+		}
+	case SubscribeHandle:
+		var handleFunc func(*message.SubscribeEvent, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.SubscribeEvent, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnSubscribe(func(ctx *Context, msg *message.SubscribeEvent) { handleFunc(msg, ctx.Writer) })
+		}
+	case UnsubscribeHandle:
+		var handleFunc func(*message.UnsubscribeEvent, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.UnsubscribeEvent, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnUnsubscribe(func(ctx *Context, msg *message.UnsubscribeEvent) { handleFunc(msg, ctx.Writer) })
+		}
+	case ScanHandle:
+		var handleFunc func(*message.ScanEvent, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.ScanEvent, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnScan(func(ctx *Context, msg *message.ScanEvent) { handleFunc(msg, ctx.Writer) })
+		}
+	case LocationEventHandle:
+		var handleFunc func(*message.LocationEvent, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.LocationEvent, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnLocationEvent(func(ctx *Context, msg *message.LocationEvent) { handleFunc(msg, ctx.Writer) })
+		}
+	case ClickHandle:
+		var handleFunc func(*message.ClickEvent, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.ClickEvent, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnClick(func(ctx *Context, msg *message.ClickEvent) { handleFunc(msg, ctx.Writer) })
 		}
+	case ViewHandle:
+		var handleFunc func(*message.ViewEvent, http.ResponseWriter)
+		if handleFunc, ok = handle.(func(*message.ViewEvent, http.ResponseWriter)); ok && handleFunc != nil {
+			svr.OnView(func(ctx *Context, msg *message.ViewEvent) { handleFunc(msg, ctx.Writer) })
+		}
+	}
+	if !ok {
+		return fmt.Errorf("wxserver: invalid handle %T for %s", handle, handleType)
+	}
+	return nil
+}
+
+// RegisterClickHandle 按菜单EventKey注册点击事件处理器
+//
+// Deprecated: 使用OnClickKey
+func (svr *Server) RegisterClickHandle(eventKey string, handle func(*message.ClickEvent, http.ResponseWriter)) {
+	if handle != nil {
+		svr.OnClickKey(eventKey, func(ctx *Context, msg *message.ClickEvent) { handle(msg, ctx.Writer) })
 	}
 }
 
 // Start 启动服务，监听80端口
 func (svr *Server) Start() {
-	http.HandleFunc("/", svr.serverHandle)
+	http.Handle("/", svr)
 	http.HandleFunc("/check", svr.ConnectServer)
 	err := http.ListenAndServe(":80", nil)
 	if err == nil {
@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshAhead 默认在AccessToken过期前提前刷新的时间
+const DefaultRefreshAhead = 5 * time.Minute
+
+// ErrNoAppInfo 未设置appid或appsecret
+var ErrNoAppInfo = errors.New("wxserver: appid or appsecret not set")
+
+// Token 缓存的AccessToken
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Valid 判断AccessToken在ahead时间之后是否仍然有效
+func (t *Token) Valid(ahead time.Duration) bool {
+	return t != nil && t.AccessToken != "" && time.Now().Add(ahead).Before(t.ExpiresAt)
+}
+
+// TokenStore AccessToken存储接口，多实例部署时可使用共享存储
+type TokenStore interface {
+	// Load 读取AccessToken，没有缓存时返回nil
+	Load() (*Token, error)
+	// Save 保存AccessToken，token为nil时清除缓存
+	Save(token *Token) error
+}
+
+// TokenLocker 分布式锁接口，多实例部署时保证同一时刻只有一个实例刷新AccessToken
+//
+// Lock在接口调用的请求路径上执行，不会随调用方ctx取消，实现需自带超时，超时后返回错误
+type TokenLocker interface {
+	Lock() error
+	Unlock() error
+}
+
+// MemoryTokenStore 内存AccessToken存储
+type MemoryTokenStore struct {
+	mu    sync.RWMutex
+	token *Token
+}
+
+// NewMemoryTokenStore 创建内存AccessToken存储
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return new(MemoryTokenStore)
+}
+
+// Load 读取AccessToken
+func (ms *MemoryTokenStore) Load() (*Token, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if ms.token == nil {
+		return nil, nil
+	}
+	token := *ms.token
+	return &token, nil
+}
+
+// Save 保存AccessToken
+func (ms *MemoryTokenStore) Save(token *Token) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if token == nil {
+		ms.token = nil
+		return nil
+	}
+	saved := *token
+	ms.token = &saved
+	return nil
+}
+
+// FileTokenStore 文件AccessToken存储
+type FileTokenStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileTokenStore 创建文件AccessToken存储
+func NewFileTokenStore(path string) *FileTokenStore {
+	fs := new(FileTokenStore)
+	fs.path = path
+	return fs
+}
+
+// Load 读取AccessToken
+func (fs *FileTokenStore) Load() (*Token, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	content, err := ioutil.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		return nil, nil
+	}
+	token := new(Token)
+	if err := json.Unmarshal(content, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// Save 保存AccessToken，先写临时文件再重命名以免读到不完整的内容
+func (fs *FileTokenStore) Save(token *Token) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if token == nil {
+		token = &Token{}
+	}
+	content, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	// 临时文件名各不相同，多个实例共享同一文件时不会互相覆盖写到一半的内容
+	tmpFile, err := ioutil.TempFile(filepath.Dir(fs.path), filepath.Base(fs.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	_, err = tmpFile.Write(content)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, fs.path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+	}
+	return err
+}
+
+// TokenErrorHandle AccessToken已获取但保存失败时的回调
+type TokenErrorHandle func(err error)
+
+// TokenManager AccessToken管理器，并发安全，同一时刻只会有一个刷新请求
+type TokenManager struct {
+	appid        string
+	appsecret    string
+	store        TokenStore
+	locker       TokenLocker
+	refreshAhead time.Duration
+	client       *APIClient
+	errorHandle  TokenErrorHandle
+
+	// refreshSem 容量为1，用作可被ctx取消等待的刷新锁
+	refreshSem chan struct{}
+	mu         sync.RWMutex
+	current    *Token
+}
+
+// NewTokenManager 创建AccessToken管理器，默认使用内存存储
+func NewTokenManager(appid string, appsecret string) *TokenManager {
+	tm := new(TokenManager)
+	tm.appid = appid
+	tm.appsecret = appsecret
+	tm.store = NewMemoryTokenStore()
+	tm.refreshAhead = DefaultRefreshAhead
+	tm.client = DefaultClient
+	tm.refreshSem = make(chan struct{}, 1)
+	return tm
+}
+
+// SetStore 设置AccessToken存储
+func (tm *TokenManager) SetStore(store TokenStore) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.store = store
+	tm.current = nil
+}
+
+// SetLocker 设置分布式锁
+func (tm *TokenManager) SetLocker(locker TokenLocker) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.locker = locker
+}
+
+// SetRefreshAhead 设置在过期前提前刷新的时间
+func (tm *TokenManager) SetRefreshAhead(ahead time.Duration) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.refreshAhead = ahead
+}
+
+// SetClient 设置获取AccessToken所用的接口客户端
+func (tm *TokenManager) SetClient(client *APIClient) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.client = client
+}
+
+// SetErrorHandle 设置AccessToken保存失败时的回调，未设置时输出日志
+func (tm *TokenManager) SetErrorHandle(handle TokenErrorHandle) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.errorHandle = handle
+}
+
+func (tm *TokenManager) setAppInfo(appid string, appsecret string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.appid = appid
+	tm.appsecret = appsecret
+	tm.current = nil
+}
+
+// Token 获取AccessToken，即将过期时自动刷新
+func (tm *TokenManager) Token() (string, error) {
+	return tm.TokenContext(context.Background())
+}
+
+// TokenContext 获取AccessToken，即将过期时自动刷新，ctx取消后不再等待刷新
+func (tm *TokenManager) TokenContext(ctx context.Context) (string, error) {
+	tm.mu.RLock()
+	current := tm.current
+	ahead := tm.refreshAhead
+	tm.mu.RUnlock()
+	if current.Valid(ahead) {
+		return current.AccessToken, nil
+	}
+
+	select {
+	case tm.refreshSem <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-tm.refreshSem }()
+
+	// 等待锁期间可能已被其他请求刷新
+	tm.mu.RLock()
+	current = tm.current
+	store := tm.store
+	locker := tm.locker
+	appid := tm.appid
+	appsecret := tm.appsecret
+	client := tm.client
+	errorHandle := tm.errorHandle
+	tm.mu.RUnlock()
+	if current.Valid(ahead) {
+		return current.AccessToken, nil
+	}
+
+	// 先读存储，其他实例可能已经刷新过
+	stored, err := store.Load()
+	if err == nil && stored.Valid(ahead) {
+		tm.setCurrent(stored)
+		return stored.AccessToken, nil
+	}
+
+	if appid == "" || appsecret == "" {
+		return "", ErrNoAppInfo
+	}
+
+	if locker != nil {
+		if err := locker.Lock(); err != nil {
+			return "", err
+		}
+		defer locker.Unlock()
+
+		stored, err = store.Load()
+		if err == nil && stored.Valid(ahead) {
+			tm.setCurrent(stored)
+			return stored.AccessToken, nil
+		}
+	}
+
+	tokenInfo, err := client.fetchAccessToken(ctx, appid, appsecret)
+	if err != nil {
+		return "", err
+	}
+	token := new(Token)
+	token.AccessToken = tokenInfo.AccessToken
+	token.ExpiresAt = time.Now().Add(time.Duration(tokenInfo.ExpiresIn) * time.Second)
+	// 新AccessToken获取后旧的已失效，保存失败也要继续使用，否则每次重试都会刷新掉其他实例的AccessToken
+	tm.mu.Lock()
+	tm.current = token
+	err = store.Save(token)
+	tm.mu.Unlock()
+	if err != nil {
+		if errorHandle != nil {
+			errorHandle(err)
+		} else {
+			log.Println("Save AccessToken Error", err)
+		}
+	}
+	return token.AccessToken, nil
+}
+
+func (tm *TokenManager) setCurrent(token *Token) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.current = token
+}
+
+// Invalidate 使指定的AccessToken失效，下次获取时重新刷新
+func (tm *TokenManager) Invalidate(token string) {
+	// 只做比较后清除，不等待正在进行的刷新，与刷新后的保存通过mu互斥
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.current != nil && tm.current.AccessToken == token {
+		tm.current = nil
+	}
+
+	// 存储中的AccessToken可能已被其他实例刷新，只清除相同的AccessToken
+	stored, err := tm.store.Load()
+	if err == nil && stored != nil && stored.AccessToken == token {
+		tm.store.Save(nil)
+	}
+}
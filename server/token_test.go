@@ -0,0 +1,232 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTokenAPI 模拟微信接口，每次获取AccessToken返回T1、T2...，用T1调用接口时返回AccessToken已过期
+func newTokenAPI(t *testing.T) (*APIClient, *int32) {
+	fetches := new(int32)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cgi-bin/token":
+			n := atomic.AddInt32(fetches, 1)
+			time.Sleep(10 * time.Millisecond)
+			fmt.Fprintf(w, `{"access_token":"T%d","expires_in":7200}`, n)
+		case "/cgi-bin/test":
+			if r.URL.Query().Get("access_token") == "T1" {
+				fmt.Fprintf(w, `{"errcode":%d,"errmsg":"access_token expired"}`, ErrCodeAccessTokenExpired)
+				return
+			}
+			fmt.Fprint(w, `{"errcode":0,"errmsg":"ok"}`)
+		}
+	}))
+	t.Cleanup(api.Close)
+	client := NewAPIClient(nil)
+	client.SetBaseURL(api.URL)
+	return client, fetches
+}
+
+func newTestTokenManager(t *testing.T) (*TokenManager, *int32) {
+	client, fetches := newTokenAPI(t)
+	tm := NewTokenManager("appid", "secret")
+	tm.SetClient(client)
+	return tm, fetches
+}
+
+func TestTokenSingleFlight(t *testing.T) {
+	tm, fetches := newTestTokenManager(t)
+	var wg sync.WaitGroup
+	tokens := make([]string, 20)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			token, err := tm.Token()
+			if err != nil {
+				t.Error(err)
+			}
+			tokens[i] = token
+		}(i)
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(fetches); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+	for _, token := range tokens {
+		if token != "T1" {
+			t.Errorf("token = %q, want T1", token)
+		}
+	}
+}
+
+func TestTokenStoreReuse(t *testing.T) {
+	tm, fetches := newTestTokenManager(t)
+	store := NewMemoryTokenStore()
+	store.Save(&Token{AccessToken: "STORED", ExpiresAt: time.Now().Add(time.Hour)})
+	tm.SetStore(store)
+	if token, err := tm.Token(); err != nil || token != "STORED" {
+		t.Errorf("Token() = %q, %v, want STORED", token, err)
+	}
+	if n := atomic.LoadInt32(fetches); n != 0 {
+		t.Errorf("fetched %d times, want 0", n)
+	}
+
+	// 即将过期的AccessToken提前刷新
+	store.Save(&Token{AccessToken: "EXPIRING", ExpiresAt: time.Now().Add(time.Minute)})
+	tm.SetStore(store)
+	if token, err := tm.Token(); err != nil || token != "T1" {
+		t.Errorf("Token() = %q, %v, want T1", token, err)
+	}
+	if stored, _ := store.Load(); stored == nil || stored.AccessToken != "T1" {
+		t.Errorf("stored token = %+v, want T1", stored)
+	}
+}
+
+// lockerFunc 加锁时执行fn，模拟等待锁期间其他实例已刷新
+type lockerFunc func()
+
+func (fn lockerFunc) Lock() error {
+	fn()
+	return nil
+}
+
+func (fn lockerFunc) Unlock() error {
+	return nil
+}
+
+func TestTokenLockerRecheck(t *testing.T) {
+	tm, fetches := newTestTokenManager(t)
+	store := NewMemoryTokenStore()
+	tm.SetStore(store)
+	tm.SetLocker(lockerFunc(func() {
+		store.Save(&Token{AccessToken: "OTHER", ExpiresAt: time.Now().Add(time.Hour)})
+	}))
+	if token, err := tm.Token(); err != nil || token != "OTHER" {
+		t.Errorf("Token() = %q, %v, want OTHER", token, err)
+	}
+	if n := atomic.LoadInt32(fetches); n != 0 {
+		t.Errorf("fetched %d times, want 0", n)
+	}
+}
+
+// failingStore 保存总是失败的存储
+type failingStore struct {
+	MemoryTokenStore
+}
+
+var errSave = errors.New("save failed")
+
+func (fs *failingStore) Save(token *Token) error {
+	return errSave
+}
+
+func TestTokenSaveError(t *testing.T) {
+	tm, fetches := newTestTokenManager(t)
+	tm.SetStore(new(failingStore))
+	var handleErr error
+	tm.SetErrorHandle(func(err error) { handleErr = err })
+
+	for i := 0; i < 3; i++ {
+		if token, err := tm.Token(); err != nil || token != "T1" {
+			t.Fatalf("Token() = %q, %v, want T1", token, err)
+		}
+	}
+	if n := atomic.LoadInt32(fetches); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+	if handleErr != errSave {
+		t.Errorf("ErrorHandle got %v, want %v", handleErr, errSave)
+	}
+}
+
+func TestTokenInvalidateRetry(t *testing.T) {
+	client, fetches := newTokenAPI(t)
+	tm := NewTokenManager("appid", "secret")
+	tm.SetClient(client)
+	apiClient := NewAPIClient(tm)
+	apiClient.SetBaseURL(client.BaseURL())
+
+	if err := apiClient.Get("/cgi-bin/test", nil, nil); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if n := atomic.LoadInt32(fetches); n != 2 {
+		t.Errorf("fetched %d times, want 2", n)
+	}
+
+	// 使已被替换的旧AccessToken失效不影响当前AccessToken
+	tm.Invalidate("T1")
+	if token, err := tm.Token(); err != nil || token != "T2" {
+		t.Errorf("Token() = %q, %v, want T2", token, err)
+	}
+	if n := atomic.LoadInt32(fetches); n != 2 {
+		t.Errorf("fetched %d times, want 2", n)
+	}
+}
+
+func TestFileTokenStore(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "token.json")
+	fs := NewFileTokenStore(path)
+	if token, err := fs.Load(); token != nil || err != nil {
+		t.Errorf("Load() on missing file = %+v, %v, want nil, nil", token, err)
+	}
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	if err := fs.Save(&Token{AccessToken: "T", ExpiresAt: expiresAt}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	token, err := NewFileTokenStore(path).Load()
+	if err != nil || token == nil || token.AccessToken != "T" || !token.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Load() = %+v, %v", token, err)
+	}
+
+	if err := fs.Save(nil); err != nil {
+		t.Fatalf("Save(nil): %v", err)
+	}
+	if token, err := fs.Load(); err != nil || token.Valid(0) {
+		t.Errorf("Load() after clear = %+v, %v, want invalid token", token, err)
+	}
+
+	// 只留下目标文件，不残留临时文件
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Name() != "token.json" {
+		t.Errorf("files in dir = %d, want only token.json", len(files))
+	}
+}
+
+func TestInvalidateDuringRefresh(t *testing.T) {
+	tm, _ := newTestTokenManager(t)
+	locked := make(chan struct{})
+	release := make(chan struct{})
+	tm.SetLocker(lockerFunc(func() {
+		close(locked)
+		<-release
+	}))
+	go tm.Token()
+	<-locked
+	defer close(release)
+
+	done := make(chan struct{})
+	go func() {
+		tm.Invalidate("T0")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Invalidate blocked by refresh in progress")
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"coding.net/cherrysd/wxserver/util"
+)
+
+// DefaultTimestampWindow 默认允许的请求时间戳偏差
+const DefaultTimestampWindow = 5 * time.Minute
+
+// VerifyErrorCode 请求校验失败原因
+type VerifyErrorCode int
+
+// 请求校验失败原因枚举
+const (
+	// MissingParams 缺少signature/timestamp/nonce参数
+	MissingParams VerifyErrorCode = iota + 1
+	// InvalidSignature 签名不匹配
+	InvalidSignature
+	// InvalidTimestamp 时间戳格式错误
+	InvalidTimestamp
+	// ExpiredTimestamp 时间戳超出允许的偏差范围
+	ExpiredTimestamp
+)
+
+// VerifyError 请求校验失败错误
+type VerifyError struct {
+	Code      VerifyErrorCode
+	Timestamp string
+	Nonce     string
+}
+
+func (e *VerifyError) Error() string {
+	switch e.Code {
+	case MissingParams:
+		return "wxserver: missing signature params"
+	case InvalidSignature:
+		return "wxserver: invalid signature"
+	case InvalidTimestamp:
+		return fmt.Sprintf("wxserver: invalid timestamp %q", e.Timestamp)
+	case ExpiredTimestamp:
+		return fmt.Sprintf("wxserver: timestamp %s out of window", e.Timestamp)
+	}
+	return "wxserver: verify request failed"
+}
+
+// ErrorHandle 请求处理失败时的回调
+type ErrorHandle func(err error, r *http.Request)
+
+// SetTimestampWindow 设置允许的请求时间戳偏差，为0时不校验时间戳
+func (svr *Server) SetTimestampWindow(window time.Duration) {
+	svr.timestampWindow = window
+}
+
+// SetErrorHandle 设置请求处理失败时的回调，可用于日志与统计
+func (svr *Server) SetErrorHandle(handle ErrorHandle) {
+	svr.errorHandle = handle
+}
+
+// VerifyRequest 校验微信服务器请求的signature与timestamp
+func (svr *Server) VerifyRequest(r *http.Request) error {
+	query := r.URL.Query()
+	signature := query.Get("signature")
+	timestamp := query.Get("timestamp")
+	nonce := query.Get("nonce")
+	if signature == "" || timestamp == "" || nonce == "" {
+		return &VerifyError{Code: MissingParams, Timestamp: timestamp, Nonce: nonce}
+	}
+
+	if svr.timestampWindow > 0 {
+		ts, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return &VerifyError{Code: InvalidTimestamp, Timestamp: timestamp, Nonce: nonce}
+		}
+		diff := time.Duration(util.GetCurrTimeStamp()-ts) * time.Second
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff > svr.timestampWindow {
+			return &VerifyError{Code: ExpiredTimestamp, Timestamp: timestamp, Nonce: nonce}
+		}
+	}
+
+	if !util.CheckSignature(svr.checkToken, timestamp, nonce, signature) {
+		return &VerifyError{Code: InvalidSignature, Timestamp: timestamp, Nonce: nonce}
+	}
+	return nil
+}
+
+// ServeHTTP 统一回调入口，GET请求用于接入校验，POST请求校验签名后分发消息
+func (svr *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		svr.ConnectServer(w, r)
+	case http.MethodPost:
+		if err := svr.VerifyRequest(r); err != nil {
+			svr.handleError(err, r)
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		svr.serverHandle(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (svr *Server) handleError(err error, r *http.Request) {
+	if svr.errorHandle != nil {
+		svr.errorHandle(err, r)
+		return
+	}
+	log.Println("Handle Request Error", err)
+}
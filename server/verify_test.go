@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyRequest(t *testing.T) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	withSignature := func(query url.Values, signature string) url.Values {
+		query.Set("signature", signature)
+		return query
+	}
+	without := func(query url.Values, key string) url.Values {
+		query.Del(key)
+		return query
+	}
+
+	cases := []struct {
+		name  string
+		query url.Values
+		code  VerifyErrorCode
+	}{
+		{"valid", signedQuery(now, "nonce"), 0},
+		{"missing signature", without(signedQuery(now, "nonce"), "signature"), MissingParams},
+		{"missing timestamp", without(signedQuery(now, "nonce"), "timestamp"), MissingParams},
+		{"missing nonce", without(signedQuery(now, "nonce"), "nonce"), MissingParams},
+		{"bad signature", withSignature(signedQuery(now, "nonce"), testSignature(now, "other")), InvalidSignature},
+		{"bad timestamp", signedQuery("not-a-number", "nonce"), InvalidTimestamp},
+		{"expired timestamp", signedQuery(stale, "nonce"), ExpiredTimestamp},
+	}
+
+	svr := NewServer(testToken)
+	for _, tc := range cases {
+		err := svr.VerifyRequest(httptest.NewRequest(http.MethodPost, "/?"+tc.query.Encode(), nil))
+		if tc.code == 0 {
+			if err != nil {
+				t.Errorf("%s: err = %v, want nil", tc.name, err)
+			}
+			continue
+		}
+		verifyErr, ok := err.(*VerifyError)
+		if !ok {
+			t.Errorf("%s: err = %v, want *VerifyError", tc.name, err)
+			continue
+		}
+		if verifyErr.Code != tc.code {
+			t.Errorf("%s: code = %d, want %d", tc.name, verifyErr.Code, tc.code)
+		}
+	}
+
+	// 时间戳窗口为0时不校验时间戳
+	svr.SetTimestampWindow(0)
+	if err := svr.VerifyRequest(httptest.NewRequest(http.MethodPost, "/?"+signedQuery(stale, "nonce").Encode(), nil)); err != nil {
+		t.Errorf("stale timestamp without window: err = %v, want nil", err)
+	}
+}
+
+func TestServeHTTPVerify(t *testing.T) {
+	svr := NewServer(testToken)
+	var handleErr error
+	svr.SetErrorHandle(func(err error, r *http.Request) { handleErr = err })
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	query := signedQuery(now, "nonce")
+	query.Set("echostr", "echo123")
+	w := httptest.NewRecorder()
+	svr.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil))
+	if w.Code != http.StatusOK || w.Body.String() != "echo123" {
+		t.Errorf("GET = %d %q, want 200 \"echo123\"", w.Code, w.Body.String())
+	}
+
+	query.Set("signature", testSignature(now, "other"))
+	w = httptest.NewRecorder()
+	svr.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil))
+	if w.Body.String() != "" {
+		t.Errorf("GET with bad signature echoed %q", w.Body.String())
+	}
+
+	handleErr = nil
+	w = httptest.NewRecorder()
+	svr.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/?"+query.Encode(), strings.NewReader("<xml></xml>")))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("POST with bad signature status = %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if verifyErr, ok := handleErr.(*VerifyError); !ok || verifyErr.Code != InvalidSignature {
+		t.Errorf("ErrorHandle got %v, want InvalidSignature", handleErr)
+	}
+
+	w = httptest.NewRecorder()
+	svr.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("PUT status = %d, want %d", w.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,61 @@
+package shorturl
+
+import (
+	"context"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+// 长链接转短链接接口(long2short)已被微信停用，这里只提供短key的生成与查询
+const (
+	genPath   = "/cgi-bin/shorten/gen"
+	fetchPath = "/cgi-bin/shorten/fetch"
+)
+
+// 短key有效期限制，单位秒
+const (
+	DefaultExpireSeconds = 2592000
+	MaxExpireSeconds     = 2592000
+)
+
+// LongData 短key对应的长信息
+type LongData struct {
+	LongData      string `json:"long_data"`
+	CreateTime    int64  `json:"create_time"`
+	ExpireSeconds int    `json:"expire_seconds"`
+}
+
+// Gen 将长信息生成短key，expireSeconds为0时使用默认的30天
+func Gen(svr *server.Server, longData string, expireSeconds int) (string, error) {
+	return GenContext(context.Background(), svr, longData, expireSeconds)
+}
+
+// GenContext 将长信息生成短key，expireSeconds为0时使用默认的30天
+func GenContext(ctx context.Context, svr *server.Server, longData string, expireSeconds int) (string, error) {
+	request := struct {
+		LongData      string `json:"long_data"`
+		ExpireSeconds int    `json:"expire_seconds,omitempty"`
+	}{longData, expireSeconds}
+	result := struct {
+		ShortKey string `json:"short_key"`
+	}{}
+	err := svr.Client().PostJSONContext(ctx, genPath, nil, &request, &result)
+	return result.ShortKey, err
+}
+
+// Fetch 获取短key对应的长信息
+func Fetch(svr *server.Server, shortKey string) (*LongData, error) {
+	return FetchContext(context.Background(), svr, shortKey)
+}
+
+// FetchContext 获取短key对应的长信息
+func FetchContext(ctx context.Context, svr *server.Server, shortKey string) (*LongData, error) {
+	request := struct {
+		ShortKey string `json:"short_key"`
+	}{shortKey}
+	result := new(LongData)
+	if err := svr.Client().PostJSONContext(ctx, fetchPath, nil, &request, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,165 @@
+package template
+
+import (
+	"context"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const (
+	sendPath           = "/cgi-bin/message/template/send"
+	getAllPath         = "/cgi-bin/template/get_all_private_template"
+	addTemplatePath    = "/cgi-bin/template/api_add_template"
+	deleteTemplatePath = "/cgi-bin/template/del_private_template"
+	setIndustryPath    = "/cgi-bin/template/api_set_industry"
+	getIndustryPath    = "/cgi-bin/template/get_industry"
+)
+
+// DataItem 模板数据项
+type DataItem struct {
+	Value string `json:"value"`
+	Color string `json:"color,omitempty"`
+}
+
+// MiniProgram 点击模板消息跳转的小程序
+type MiniProgram struct {
+	AppID    string `json:"appid"`
+	PagePath string `json:"pagepath,omitempty"`
+}
+
+// Message 模板消息，URL与MiniProgram同时填写时优先跳转小程序
+type Message struct {
+	ToUser      string              `json:"touser"`
+	TemplateID  string              `json:"template_id"`
+	URL         string              `json:"url,omitempty"`
+	MiniProgram *MiniProgram        `json:"miniprogram,omitempty"`
+	ClientMsgID string              `json:"client_msg_id,omitempty"`
+	Data        map[string]DataItem `json:"data"`
+}
+
+// Template 私有模板信息
+type Template struct {
+	TemplateID      string `json:"template_id"`
+	Title           string `json:"title"`
+	PrimaryIndustry string `json:"primary_industry"`
+	DeputyIndustry  string `json:"deputy_industry"`
+	Content         string `json:"content"`
+	Example         string `json:"example"`
+}
+
+// IndustryClass 行业分类
+type IndustryClass struct {
+	FirstClass  string `json:"first_class"`
+	SecondClass string `json:"second_class"`
+}
+
+// Industry 帐号设置的行业信息
+type Industry struct {
+	PrimaryIndustry   IndustryClass `json:"primary_industry"`
+	SecondaryIndustry IndustryClass `json:"secondary_industry"`
+}
+
+// NewMessage 新建模板消息
+func NewMessage(toUser string, templateID string) *Message {
+	msg := new(Message)
+	msg.ToUser = toUser
+	msg.TemplateID = templateID
+	msg.Data = make(map[string]DataItem)
+	return msg
+}
+
+// AddData 添加模板数据项，color为空时使用默认颜色
+func (msg *Message) AddData(key string, value string, color string) *Message {
+	if msg.Data == nil {
+		msg.Data = make(map[string]DataItem)
+	}
+	msg.Data[key] = DataItem{Value: value, Color: color}
+	return msg
+}
+
+// Send 发送模板消息，返回消息ID
+func Send(svr *server.Server, msg *Message) (int64, error) {
+	return SendContext(context.Background(), svr, msg)
+}
+
+// SendContext 发送模板消息，返回消息ID
+func SendContext(ctx context.Context, svr *server.Server, msg *Message) (int64, error) {
+	result := struct {
+		MsgID int64 `json:"msgid"`
+	}{}
+	err := svr.Client().PostJSONContext(ctx, sendPath, nil, msg, &result)
+	return result.MsgID, err
+}
+
+// GetAllTemplates 获取已添加的全部模板
+func GetAllTemplates(svr *server.Server) ([]Template, error) {
+	return GetAllTemplatesContext(context.Background(), svr)
+}
+
+// GetAllTemplatesContext 获取已添加的全部模板
+func GetAllTemplatesContext(ctx context.Context, svr *server.Server) ([]Template, error) {
+	result := struct {
+		TemplateList []Template `json:"template_list"`
+	}{}
+	err := svr.Client().GetContext(ctx, getAllPath, nil, &result)
+	return result.TemplateList, err
+}
+
+// AddTemplate 从模板库添加模板，返回模板ID
+func AddTemplate(svr *server.Server, templateIDShort string) (string, error) {
+	return AddTemplateContext(context.Background(), svr, templateIDShort)
+}
+
+// AddTemplateContext 从模板库添加模板，返回模板ID
+func AddTemplateContext(ctx context.Context, svr *server.Server, templateIDShort string) (string, error) {
+	request := struct {
+		TemplateIDShort string `json:"template_id_short"`
+	}{templateIDShort}
+	result := struct {
+		TemplateID string `json:"template_id"`
+	}{}
+	err := svr.Client().PostJSONContext(ctx, addTemplatePath, nil, &request, &result)
+	return result.TemplateID, err
+}
+
+// DeleteTemplate 删除模板
+func DeleteTemplate(svr *server.Server, templateID string) error {
+	return DeleteTemplateContext(context.Background(), svr, templateID)
+}
+
+// DeleteTemplateContext 删除模板
+func DeleteTemplateContext(ctx context.Context, svr *server.Server, templateID string) error {
+	request := struct {
+		TemplateID string `json:"template_id"`
+	}{templateID}
+	return svr.Client().PostJSONContext(ctx, deleteTemplatePath, nil, &request, nil)
+}
+
+// SetIndustry 设置所属行业，参数为行业代码
+func SetIndustry(svr *server.Server, primaryIndustryID string, secondaryIndustryID string) error {
+	return SetIndustryContext(context.Background(), svr, primaryIndustryID, secondaryIndustryID)
+}
+
+// SetIndustryContext 设置所属行业，参数为行业代码
+func SetIndustryContext(ctx context.Context, svr *server.Server, primaryIndustryID string, secondaryIndustryID string) error {
+	request := struct {
+		IndustryID1 string `json:"industry_id1"`
+		IndustryID2 string `json:"industry_id2"`
+	}{primaryIndustryID, secondaryIndustryID}
+	return svr.Client().PostJSONContext(ctx, setIndustryPath, nil, &request, nil)
+}
+
+// GetIndustry 获取设置的行业信息
+func GetIndustry(svr *server.Server) (*Industry, error) {
+	return GetIndustryContext(context.Background(), svr)
+}
+
+// GetIndustryContext 获取设置的行业信息
+func GetIndustryContext(ctx context.Context, svr *server.Server) (*Industry, error) {
+	industry := new(Industry)
+	err := svr.Client().GetContext(ctx, getIndustryPath, nil, industry)
+	if err != nil {
+		return nil, err
+	}
+	return industry, nil
+}
@@ -0,0 +1,65 @@
+package user
+
+import (
+	"context"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const (
+	getBlacklistPath     = "/cgi-bin/tags/members/getblacklist"
+	batchBlacklistPath   = "/cgi-bin/tags/members/batchblacklist"
+	batchUnblacklistPath = "/cgi-bin/tags/members/batchunblacklist"
+)
+
+// batchBlacklistLimit 批量拉黑每次最多20个用户
+const batchBlacklistLimit = 20
+
+// GetBlacklist 获取一页黑名单，每页最多10000个，beginOpenID为空时从头开始
+func GetBlacklist(svr *server.Server, beginOpenID string) (*FollowerList, error) {
+	return GetBlacklistContext(context.Background(), svr, beginOpenID)
+}
+
+// GetBlacklistContext 获取一页黑名单，每页最多10000个，beginOpenID为空时从头开始
+func GetBlacklistContext(ctx context.Context, svr *server.Server, beginOpenID string) (*FollowerList, error) {
+	request := struct {
+		BeginOpenID string `json:"begin_openid"`
+	}{beginOpenID}
+	list := new(FollowerList)
+	if err := svr.Client().PostJSONContext(ctx, getBlacklistPath, nil, &request, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// BatchBlacklist 批量拉黑用户，超过20个时自动分批请求
+func BatchBlacklist(svr *server.Server, openIDs []string) error {
+	return BatchBlacklistContext(context.Background(), svr, openIDs)
+}
+
+// BatchBlacklistContext 批量拉黑用户，超过20个时自动分批请求
+func BatchBlacklistContext(ctx context.Context, svr *server.Server, openIDs []string) error {
+	return batchBlacklist(ctx, svr, batchBlacklistPath, openIDs)
+}
+
+// BatchUnblacklist 批量取消拉黑用户，超过20个时自动分批请求
+func BatchUnblacklist(svr *server.Server, openIDs []string) error {
+	return BatchUnblacklistContext(context.Background(), svr, openIDs)
+}
+
+// BatchUnblacklistContext 批量取消拉黑用户，超过20个时自动分批请求
+func BatchUnblacklistContext(ctx context.Context, svr *server.Server, openIDs []string) error {
+	return batchBlacklist(ctx, svr, batchUnblacklistPath, openIDs)
+}
+
+func batchBlacklist(ctx context.Context, svr *server.Server, path string, openIDs []string) error {
+	for _, chunk := range chunkOpenIDs(openIDs, batchBlacklistLimit) {
+		request := struct {
+			OpenIDList []string `json:"openid_list"`
+		}{chunk}
+		if err := svr.Client().PostJSONContext(ctx, path, nil, &request, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package user
+
+import (
+	"context"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+// FollowerIterator 按next_openid分页遍历关注者列表
+//
+//	iter := user.NewFollowerIterator(svr, "")
+//	for iter.HasNext() {
+//		openIDs, err := iter.Next()
+//		...
+//	}
+type FollowerIterator struct {
+	svr        *server.Server
+	nextOpenID string
+	total      int
+	done       bool
+}
+
+// NewFollowerIterator 创建关注者列表迭代器，nextOpenID为空时从头开始
+func NewFollowerIterator(svr *server.Server, nextOpenID string) *FollowerIterator {
+	iter := new(FollowerIterator)
+	iter.svr = svr
+	iter.nextOpenID = nextOpenID
+	return iter
+}
+
+// HasNext 是否还有下一页
+func (iter *FollowerIterator) HasNext() bool {
+	return !iter.done
+}
+
+// Next 获取下一页关注者的OpenID，出错后可再次调用重试
+func (iter *FollowerIterator) Next() ([]string, error) {
+	return iter.NextContext(context.Background())
+}
+
+// NextContext 获取下一页关注者的OpenID，出错后可再次调用重试
+func (iter *FollowerIterator) NextContext(ctx context.Context) ([]string, error) {
+	if iter.done {
+		return nil, nil
+	}
+	list, err := GetFollowersContext(ctx, iter.svr, iter.nextOpenID)
+	if err != nil {
+		return nil, err
+	}
+	iter.total = list.Total
+	// 最后一页之后count为0，next_openid为空时也表示已经拉取完毕
+	if list.Count == 0 || list.NextOpenID == "" {
+		iter.done = true
+	}
+	iter.nextOpenID = list.NextOpenID
+	return list.Data.OpenID, nil
+}
+
+// NextOpenID 返回下一页的起始OpenID，可用于保存遍历进度
+func (iter *FollowerIterator) NextOpenID() string {
+	return iter.nextOpenID
+}
+
+// Total 返回关注者总数，调用Next之后才有值
+func (iter *FollowerIterator) Total() int {
+	return iter.total
+}
@@ -0,0 +1,175 @@
+package user
+
+import (
+	"context"
+
+	"coding.net/cherrysd/wxserver/menu"
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const (
+	createTagPath      = "/cgi-bin/tags/create"
+	getTagsPath        = "/cgi-bin/tags/get"
+	updateTagPath      = "/cgi-bin/tags/update"
+	deleteTagPath      = "/cgi-bin/tags/delete"
+	getTagUsersPath    = "/cgi-bin/user/tag/get"
+	batchTaggingPath   = "/cgi-bin/tags/members/batchtagging"
+	batchUntaggingPath = "/cgi-bin/tags/members/batchuntagging"
+	getUserTagsPath    = "/cgi-bin/tags/getidlist"
+)
+
+// batchTaggingLimit 批量打标签每次最多50个用户
+const batchTaggingLimit = 50
+
+// Tag 用户标签
+type Tag struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count,omitempty"`
+}
+
+// MatchRule 返回按该标签匹配的个性化菜单规则
+func (tag *Tag) MatchRule() *menu.MatchRule {
+	return menu.NewTagMatchRule(tag.ID)
+}
+
+type tagRequest struct {
+	Tag Tag `json:"tag"`
+}
+
+// CreateTag 创建标签
+func CreateTag(svr *server.Server, name string) (*Tag, error) {
+	return CreateTagContext(context.Background(), svr, name)
+}
+
+// CreateTagContext 创建标签
+func CreateTagContext(ctx context.Context, svr *server.Server, name string) (*Tag, error) {
+	request := tagRequest{Tag{Name: name}}
+	result := tagRequest{}
+	if err := svr.Client().PostJSONContext(ctx, createTagPath, nil, &request, &result); err != nil {
+		return nil, err
+	}
+	return &result.Tag, nil
+}
+
+// GetTags 获取已创建的标签
+func GetTags(svr *server.Server) ([]Tag, error) {
+	return GetTagsContext(context.Background(), svr)
+}
+
+// GetTagsContext 获取已创建的标签
+func GetTagsContext(ctx context.Context, svr *server.Server) ([]Tag, error) {
+	result := struct {
+		Tags []Tag `json:"tags"`
+	}{}
+	err := svr.Client().GetContext(ctx, getTagsPath, nil, &result)
+	return result.Tags, err
+}
+
+// UpdateTag 修改标签名
+func UpdateTag(svr *server.Server, tagID int, name string) error {
+	return UpdateTagContext(context.Background(), svr, tagID, name)
+}
+
+// UpdateTagContext 修改标签名
+func UpdateTagContext(ctx context.Context, svr *server.Server, tagID int, name string) error {
+	request := tagRequest{Tag{ID: tagID, Name: name}}
+	return svr.Client().PostJSONContext(ctx, updateTagPath, nil, &request, nil)
+}
+
+// DeleteTag 删除标签
+func DeleteTag(svr *server.Server, tagID int) error {
+	return DeleteTagContext(context.Background(), svr, tagID)
+}
+
+// DeleteTagContext 删除标签
+func DeleteTagContext(ctx context.Context, svr *server.Server, tagID int) error {
+	request := struct {
+		Tag struct {
+			ID int `json:"id"`
+		} `json:"tag"`
+	}{}
+	request.Tag.ID = tagID
+	return svr.Client().PostJSONContext(ctx, deleteTagPath, nil, &request, nil)
+}
+
+// GetTagUsers 获取标签下的一页粉丝，nextOpenID为空时从头开始
+func GetTagUsers(svr *server.Server, tagID int, nextOpenID string) (*FollowerList, error) {
+	return GetTagUsersContext(context.Background(), svr, tagID, nextOpenID)
+}
+
+// GetTagUsersContext 获取标签下的一页粉丝，nextOpenID为空时从头开始
+func GetTagUsersContext(ctx context.Context, svr *server.Server, tagID int, nextOpenID string) (*FollowerList, error) {
+	request := struct {
+		TagID      int    `json:"tagid"`
+		NextOpenID string `json:"next_openid"`
+	}{tagID, nextOpenID}
+	list := new(FollowerList)
+	if err := svr.Client().PostJSONContext(ctx, getTagUsersPath, nil, &request, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// BatchTagging 批量为用户打标签，超过50个时自动分批请求
+func BatchTagging(svr *server.Server, tagID int, openIDs []string) error {
+	return BatchTaggingContext(context.Background(), svr, tagID, openIDs)
+}
+
+// BatchTaggingContext 批量为用户打标签，超过50个时自动分批请求
+func BatchTaggingContext(ctx context.Context, svr *server.Server, tagID int, openIDs []string) error {
+	return batchTag(ctx, svr, batchTaggingPath, tagID, openIDs)
+}
+
+// BatchUntagging 批量为用户取消标签，超过50个时自动分批请求
+func BatchUntagging(svr *server.Server, tagID int, openIDs []string) error {
+	return BatchUntaggingContext(context.Background(), svr, tagID, openIDs)
+}
+
+// BatchUntaggingContext 批量为用户取消标签，超过50个时自动分批请求
+func BatchUntaggingContext(ctx context.Context, svr *server.Server, tagID int, openIDs []string) error {
+	return batchTag(ctx, svr, batchUntaggingPath, tagID, openIDs)
+}
+
+func batchTag(ctx context.Context, svr *server.Server, path string, tagID int, openIDs []string) error {
+	for _, chunk := range chunkOpenIDs(openIDs, batchTaggingLimit) {
+		request := struct {
+			OpenIDList []string `json:"openid_list"`
+			TagID      int      `json:"tagid"`
+		}{chunk, tagID}
+		if err := svr.Client().PostJSONContext(ctx, path, nil, &request, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetUserTags 获取用户身上的标签ID列表
+func GetUserTags(svr *server.Server, openID string) ([]int, error) {
+	return GetUserTagsContext(context.Background(), svr, openID)
+}
+
+// GetUserTagsContext 获取用户身上的标签ID列表
+func GetUserTagsContext(ctx context.Context, svr *server.Server, openID string) ([]int, error) {
+	request := struct {
+		OpenID string `json:"openid"`
+	}{openID}
+	result := struct {
+		TagIDList []int `json:"tagid_list"`
+	}{}
+	err := svr.Client().PostJSONContext(ctx, getUserTagsPath, nil, &request, &result)
+	return result.TagIDList, err
+}
+
+// chunkOpenIDs 按size切分OpenID列表
+func chunkOpenIDs(openIDs []string, size int) [][]string {
+	var chunks [][]string
+	for start := 0; start < len(openIDs); start += size {
+		end := start + size
+		if end > len(openIDs) {
+			end = len(openIDs)
+		}
+		chunks = append(chunks, openIDs[start:end])
+	}
+	return chunks
+}
@@ -0,0 +1,156 @@
+package user
+
+import (
+	"context"
+	"net/url"
+
+	"coding.net/cherrysd/wxserver/server"
+)
+
+const (
+	infoPath         = "/cgi-bin/user/info"
+	batchGetInfoPath = "/cgi-bin/user/info/batchget"
+	getFollowersPath = "/cgi-bin/user/get"
+	updateRemarkPath = "/cgi-bin/user/info/updateremark"
+)
+
+// 返回国家地区语言版本
+const (
+	LangZhCN = "zh_CN"
+	LangZhTW = "zh_TW"
+	LangEn   = "en"
+)
+
+// batchGetLimit 批量获取用户信息每次最多100个
+const batchGetLimit = 100
+
+// 用户关注渠道来源
+const (
+	SceneSearch           = "ADD_SCENE_SEARCH"
+	SceneAccountMigration = "ADD_SCENE_ACCOUNT_MIGRATION"
+	SceneProfileCard      = "ADD_SCENE_PROFILE_CARD"
+	SceneQRCode           = "ADD_SCENE_QR_CODE"
+	SceneProfileLink      = "ADD_SCENE_PROFILE_LINK"
+	SceneProfileItem      = "ADD_SCENE_PROFILE_ITEM"
+	ScenePaid             = "ADD_SCENE_PAID"
+	SceneWechatAdvert     = "ADD_SCENE_WECHAT_ADVERTISEMENT"
+	SceneOthers           = "ADD_SCENE_OTHERS"
+)
+
+// Info 用户基本信息，Subscribe为0时表示用户未关注，其余字段为空
+type Info struct {
+	Subscribe      int    `json:"subscribe"`
+	OpenID         string `json:"openid"`
+	Nickname       string `json:"nickname"`
+	Sex            int    `json:"sex"`
+	Language       string `json:"language"`
+	City           string `json:"city"`
+	Province       string `json:"province"`
+	Country        string `json:"country"`
+	HeadImgURL     string `json:"headimgurl"`
+	SubscribeTime  int64  `json:"subscribe_time"`
+	UnionID        string `json:"unionid"`
+	Remark         string `json:"remark"`
+	GroupID        int    `json:"groupid"`
+	TagIDList      []int  `json:"tagid_list"`
+	SubscribeScene string `json:"subscribe_scene"`
+	QRScene        int64  `json:"qr_scene"`
+	QRSceneStr     string `json:"qr_scene_str"`
+}
+
+// Subscribed 用户是否关注了公众号
+func (info *Info) Subscribed() bool {
+	return info.Subscribe == 1
+}
+
+// FollowerList 关注者列表的一页
+type FollowerList struct {
+	Total int `json:"total"`
+	Count int `json:"count"`
+	Data  struct {
+		OpenID []string `json:"openid"`
+	} `json:"data"`
+	NextOpenID string `json:"next_openid"`
+}
+
+// GetInfo 获取用户基本信息，lang为空时返回简体中文
+func GetInfo(svr *server.Server, openID string, lang string) (*Info, error) {
+	return GetInfoContext(context.Background(), svr, openID, lang)
+}
+
+// GetInfoContext 获取用户基本信息，lang为空时返回简体中文
+func GetInfoContext(ctx context.Context, svr *server.Server, openID string, lang string) (*Info, error) {
+	params := url.Values{}
+	params.Set("openid", openID)
+	if lang != "" {
+		params.Set("lang", lang)
+	}
+	info := new(Info)
+	if err := svr.Client().GetContext(ctx, infoPath, params, info); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// BatchGetInfo 批量获取用户基本信息，超过100个时自动分批请求
+func BatchGetInfo(svr *server.Server, openIDs []string, lang string) ([]Info, error) {
+	return BatchGetInfoContext(context.Background(), svr, openIDs, lang)
+}
+
+// BatchGetInfoContext 批量获取用户基本信息，超过100个时自动分批请求
+func BatchGetInfoContext(ctx context.Context, svr *server.Server, openIDs []string, lang string) ([]Info, error) {
+	type userItem struct {
+		OpenID string `json:"openid"`
+		Lang   string `json:"lang,omitempty"`
+	}
+
+	var infos []Info
+	for _, chunk := range chunkOpenIDs(openIDs, batchGetLimit) {
+		request := struct {
+			UserList []userItem `json:"user_list"`
+		}{}
+		for _, openID := range chunk {
+			request.UserList = append(request.UserList, userItem{openID, lang})
+		}
+		result := struct {
+			UserInfoList []Info `json:"user_info_list"`
+		}{}
+		if err := svr.Client().PostJSONContext(ctx, batchGetInfoPath, nil, &request, &result); err != nil {
+			return infos, err
+		}
+		infos = append(infos, result.UserInfoList...)
+	}
+	return infos, nil
+}
+
+// GetFollowers 获取一页关注者列表，每页最多10000个，nextOpenID为空时从头开始
+func GetFollowers(svr *server.Server, nextOpenID string) (*FollowerList, error) {
+	return GetFollowersContext(context.Background(), svr, nextOpenID)
+}
+
+// GetFollowersContext 获取一页关注者列表，每页最多10000个，nextOpenID为空时从头开始
+func GetFollowersContext(ctx context.Context, svr *server.Server, nextOpenID string) (*FollowerList, error) {
+	params := url.Values{}
+	if nextOpenID != "" {
+		params.Set("next_openid", nextOpenID)
+	}
+	list := new(FollowerList)
+	if err := svr.Client().GetContext(ctx, getFollowersPath, params, list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// UpdateRemark 设置用户备注名
+func UpdateRemark(svr *server.Server, openID string, remark string) error {
+	return UpdateRemarkContext(context.Background(), svr, openID, remark)
+}
+
+// UpdateRemarkContext 设置用户备注名
+func UpdateRemarkContext(ctx context.Context, svr *server.Server, openID string, remark string) error {
+	request := struct {
+		OpenID string `json:"openid"`
+		Remark string `json:"remark"`
+	}{openID, remark}
+	return svr.Client().PostJSONContext(ctx, updateRemarkPath, nil, &request, nil)
+}
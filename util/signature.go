@@ -2,6 +2,7 @@ package util
 
 import (
 	"crypto/sha1"
+	"crypto/subtle"
 	"fmt"
 	"io"
 	"sort"
@@ -9,15 +10,35 @@ import (
 )
 
 func CheckSignature(checkToken string, timestamp string, nonce string, signatureIn string) (result bool) {
-	strlist := []string{checkToken, timestamp, nonce}
-	sort.Strings(strlist)
-	t := sha1.New()
-	io.WriteString(t, strings.Join(strlist, ""))
-	signatureGen := fmt.Sprintf("%x", t.Sum(nil))
-	if signatureGen == signatureIn {
+	signatureGen := genSignature(checkToken, timestamp, nonce)
+	if equalSignature(signatureGen, signatureIn) {
 		result = true
 	} else {
 		result = false
 	}
 	return
 }
+
+// GenMsgSignature 生成加密消息的msg_signature
+func GenMsgSignature(checkToken string, timestamp string, nonce string, encrypt string) string {
+	return genSignature(checkToken, timestamp, nonce, encrypt)
+}
+
+// CheckMsgSignature 校验加密消息的msg_signature
+func CheckMsgSignature(checkToken string, timestamp string, nonce string, encrypt string, signatureIn string) bool {
+	return equalSignature(GenMsgSignature(checkToken, timestamp, nonce, encrypt), signatureIn)
+}
+
+// equalSignature 以固定时间比较签名，避免通过响应时间逐位猜测签名
+func equalSignature(signatureGen string, signatureIn string) bool {
+	return subtle.ConstantTimeCompare([]byte(signatureGen), []byte(signatureIn)) == 1
+}
+
+func genSignature(strs ...string) string {
+	strlist := make([]string, len(strs))
+	copy(strlist, strs)
+	sort.Strings(strlist)
+	t := sha1.New()
+	io.WriteString(t, strings.Join(strlist, ""))
+	return fmt.Sprintf("%x", t.Sum(nil))
+}